@@ -0,0 +1,324 @@
+// Filename: cmd/api/authors.go
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aiycoleman/qod/internal/data"
+	"github.com/aiycoleman/qod/internal/validator"
+)
+
+// Add a new author
+func (app *application) createAuthorHandler(w http.ResponseWriter, r *http.Request) {
+	var incomingData struct {
+		Name      string   `json:"name"`
+		Aliases   []string `json:"aliases"`
+		Bio       string   `json:"bio"`
+		BirthYear *int32   `json:"birth_year"`
+		DeathYear *int32   `json:"death_year"`
+	}
+
+	err := app.readJSON(w, r, &incomingData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	author := &data.Author{
+		Name:      validator.CleanText(incomingData.Name),
+		Aliases:   cleanAliases(incomingData.Aliases),
+		Bio:       validator.CleanMultilineText(incomingData.Bio),
+		BirthYear: incomingData.BirthYear,
+		DeathYear: incomingData.DeathYear,
+	}
+
+	v := validator.New()
+
+	data.ValidateAuthor(v, author, app.config.limits)
+	if !v.IsEmpty() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	err = app.authorModel.Insert(author)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateAuthor):
+			v.AddError("name", validator.NewError("duplicate_author"))
+			app.failedValidationResponse(w, r, v)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Set a location header (the path to the newly created author)
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/authors/%d", author.ID))
+
+	data := envelope{
+		"author": author,
+	}
+	err = app.writeJSON(w, http.StatusCreated, data, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Display an author
+func (app *application) displayAuthorHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	// authors have nothing to embed, so only ?fields= is allowed
+	v := validator.New()
+	fieldset := app.readFieldset(r.URL.Query(), data.AuthorFieldsSafeList, nil, v)
+	if !v.IsEmpty() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	author, err := app.authorModel.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var shaped any = author
+	if len(fieldset.Fields) > 0 {
+		shaped, err = pickFields(author, fieldset.Fields)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	data := envelope{
+		"author": shaped,
+	}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Edit an author
+func (app *application) updateAuthorHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	author, err := app.authorModel.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// pointers so we can tell a missing field from an empty one
+	var incomingData struct {
+		Name      *string  `json:"name"`
+		Aliases   []string `json:"aliases"`
+		Bio       *string  `json:"bio"`
+		BirthYear *int32   `json:"birth_year"`
+		DeathYear *int32   `json:"death_year"`
+	}
+
+	err = app.readJSON(w, r, &incomingData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	// authors saved before text was tidied are tidied now, otherwise the
+	// checks below would fail on fields the client didn't send
+	author.Name = validator.CleanText(author.Name)
+	author.Aliases = cleanAliases(author.Aliases)
+	author.Bio = validator.CleanMultilineText(author.Bio)
+
+	if incomingData.Name != nil {
+		author.Name = validator.CleanText(*incomingData.Name)
+	}
+	if incomingData.Aliases != nil {
+		author.Aliases = cleanAliases(incomingData.Aliases)
+	}
+	if incomingData.Bio != nil {
+		author.Bio = validator.CleanMultilineText(*incomingData.Bio)
+	}
+	if incomingData.BirthYear != nil {
+		author.BirthYear = incomingData.BirthYear
+	}
+	if incomingData.DeathYear != nil {
+		author.DeathYear = incomingData.DeathYear
+	}
+
+	v := validator.New()
+
+	data.ValidateAuthor(v, author, app.config.limits)
+	if !v.IsEmpty() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	err = app.authorModel.Update(author, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateAuthor):
+			v.AddError("name", validator.NewError("duplicate_author"))
+			app.failedValidationResponse(w, r, v)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	data := envelope{
+		"author": author,
+	}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Delete an author that no quotes are attributed to
+func (app *application) deleteAuthorHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.authorModel.Delete(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrAuthorHasQuotes):
+			app.authorHasQuotesResponse(w, r)
+		case errors.Is(err, data.ErrAuthorHasTrashedQuotes):
+			app.authorHasTrashedQuotesResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	data := envelope{"message": "author successfully deleted"}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// List authors, optionally filtered by name or alias
+func (app *application) listAuthorsHandler(w http.ResponseWriter, r *http.Request) {
+	var queryParametersData struct {
+		Name string
+		data.Filters
+	}
+
+	queryParameters := r.URL.Query()
+
+	queryParametersData.Name = app.getSingleQueryParameter(queryParameters, "name", "")
+
+	v := validator.New()
+
+	queryParametersData.Filters.Page = app.getSingleIntegerParameter(queryParameters, "page", 1, v)
+	queryParametersData.Filters.PageSize = app.getSingleIntegerParameter(queryParameters, "page_size", 10, v)
+	queryParametersData.Filters.Sort = app.getSingleQueryParameter(queryParameters, "sort", "name")
+	queryParametersData.Filters.SortSafeList = []string{"id", "name", "-id", "-name"}
+
+	fieldset := app.readFieldset(queryParameters, data.AuthorFieldsSafeList, nil, v)
+
+	data.ValidateFilters(v, queryParametersData.Filters)
+	if !v.IsEmpty() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	authors, metadata, err := app.authorModel.GetAll(queryParametersData.Name, queryParametersData.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	shaped, err := pickAllFields(authors, fieldset.Fields)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	data := envelope{
+		"authors":   shaped,
+		"@metadata": metadata,
+	}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Suggest authors for a search box as the user types
+func (app *application) suggestAuthorsHandler(w http.ResponseWriter, r *http.Request) {
+	queryParameters := r.URL.Query()
+
+	prefix := strings.TrimSpace(app.getSingleQueryParameter(queryParameters, "prefix", ""))
+
+	v := validator.New()
+
+	limit := app.getSingleIntegerParameter(queryParameters, "limit", 10, v)
+	v.Check(prefix != "", "prefix", validator.NewError("required"))
+	v.Check(len(prefix) <= 25, "prefix", validator.NewError("max_bytes", "max", 25))
+	v.Check(limit > 0, "limit", validator.NewError("positive"))
+	v.Check(limit <= 25, "limit", validator.NewError("max_value", "max", 25))
+	if !v.IsEmpty() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	authors, err := app.authorModel.Suggest(prefix, app.config.search.fuzzyThreshold, limit)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	data := envelope{
+		"authors": authors,
+	}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Tidy each alias the same way as the name. A missing list stays missing
+func cleanAliases(aliases []string) []string {
+	if aliases == nil {
+		return nil
+	}
+	cleaned := make([]string, len(aliases))
+	for i, alias := range aliases {
+		cleaned[i] = validator.CleanText(alias)
+	}
+	return cleaned
+}
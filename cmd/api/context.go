@@ -0,0 +1,30 @@
+// Filename: cmd/api/context.go
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aiycoleman/qod/internal/data"
+)
+
+// A custom type for our context keys so they can't clash with other packages
+type contextKey string
+
+const userContextKey = contextKey("user")
+
+// Return a copy of the request with the user added to its context
+func (app *application) contextSetUser(r *http.Request, user *data.User) *http.Request {
+	ctx := context.WithValue(r.Context(), userContextKey, user)
+	return r.WithContext(ctx)
+}
+
+// Get the user from the request context. Only call this after the
+// authenticate middleware has run, otherwise there is no user to get
+func (app *application) contextGetUser(r *http.Request) *data.User {
+	user, ok := r.Context().Value(userContextKey).(*data.User)
+	if !ok {
+		panic("missing user value in request context")
+	}
+	return user
+}
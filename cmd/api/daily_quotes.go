@@ -0,0 +1,149 @@
+// Filename: cmd/api/daily_quotes.go
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aiycoleman/qod/internal/data"
+	"github.com/aiycoleman/qod/internal/validator"
+)
+
+// Display the quote of the day
+func (app *application) showDailyQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	dailyQuote, err := app.dailyQuoteModel.GetForDay(time.Now(), app.config.daily.window)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	data := envelope{
+		"daily_quote": dailyQuote,
+	}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Pin a specific quote to a future date
+func (app *application) pinDailyQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	day, err := app.readDateParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var incomingData struct {
+		QuoteID int64 `json:"quote_id"`
+	}
+
+	err = app.readJSON(w, r, &incomingData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	data.ValidatePin(v, day, data.StartOfDay(time.Now()))
+	v.Check(incomingData.QuoteID > 0, "quote_id", validator.NewError("required"))
+	if !v.IsEmpty() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	// make sure the quote exists before pinning it
+	quote, err := app.quoteModel.Get(incomingData.QuoteID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("quote_id", validator.NewError("unknown_quote"))
+			app.failedValidationResponse(w, r, v)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// everybody sees the quote of the day
+	v.Check(quote.Status == data.StatusApproved, "quote_id", validator.NewError("unapproved_quote"))
+	if !v.IsEmpty() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	dailyQuote, err := app.dailyQuoteModel.Pin(day, incomingData.QuoteID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	data := envelope{
+		"daily_quote": dailyQuote,
+	}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Remove the pin from a future date
+func (app *application) unpinDailyQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	day, err := app.readDateParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+	data.ValidatePin(v, day, data.StartOfDay(time.Now()))
+	if !v.IsEmpty() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	err = app.dailyQuoteModel.Unpin(day)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	data := envelope{"message": "daily quote successfully unpinned"}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Select today's and tomorrow's quotes ahead of time so the first request
+// of the day doesn't have to, then check again every hour until ctx is
+// cancelled
+func (app *application) scheduleDailyQuotes(ctx context.Context) {
+	for {
+		now := time.Now()
+		for _, day := range []time.Time{now, now.AddDate(0, 0, 1)} {
+			_, err := app.dailyQuoteModel.GetForDay(day, app.config.daily.window)
+			if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+				app.logger.Error(err.Error(), "day", day.Format(data.DayLayout))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Hour):
+		}
+	}
+}
@@ -2,8 +2,13 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+
+	"github.com/aiycoleman/qod/internal/data"
+	"github.com/aiycoleman/qod/internal/policy"
+	"github.com/aiycoleman/qod/internal/validator"
 )
 
 // log an error message
@@ -54,9 +59,24 @@ func (app *application) badRequestResponse(w http.ResponseWriter, r *http.Reques
 	app.errorResponseJSON(w, r, http.StatusBadRequest, err.Error())
 }
 
-// How to responds to validation errors in HTTP requests
-func (a *application) failedValidationResponse(w http.ResponseWriter, r *http.Request, errors map[string]string) {
-	a.errorResponseJSON(w, r, http.StatusUnprocessableEntity, errors)
+// How to responds to validation errors in HTTP requests.
+// "error" keeps the field to message shape older clients read, "errors"
+// has every error for each field with its code and params. Messages are
+// in the language picked by the Accept-Language header
+func (a *application) failedValidationResponse(w http.ResponseWriter, r *http.Request, v *validator.Validator) {
+	lang := negotiateLanguage(r.Header.Get("Accept-Language"))
+	errors, fieldErrors := v.Localize(lang)
+
+	headers := make(http.Header)
+	headers.Set("Content-Language", lang)
+	headers.Add("Vary", "Accept-Language")
+
+	errorData := envelope{"error": errors, "errors": fieldErrors}
+	err := a.writeJSON(w, http.StatusUnprocessableEntity, errorData, headers)
+	if err != nil {
+		a.logError(r, err)
+		w.WriteHeader(500)
+	}
 }
 
 // Send and error response if rate limit exceeded(429 - too many requests)
@@ -70,3 +90,117 @@ func (app *application) editConflictResponse(w http.ResponseWriter, r *http.Requ
 	message := "unable to update the record due to an edit conflict, please try again"
 	app.errorResponseJSON(w, r, http.StatusConflict, message)
 }
+
+// send an error response if the email or password is wrong(401)
+func (app *application) invalidCredentialsResponse(w http.ResponseWriter, r *http.Request) {
+	message := "invalid authentication credentials"
+	app.errorResponseJSON(w, r, http.StatusUnauthorized, message)
+}
+
+// send an error response if the token is missing a part, wrong or expired(401)
+func (app *application) invalidAuthenticationTokenResponse(w http.ResponseWriter, r *http.Request) {
+	// let the client know we expect a bearer token
+	w.Header().Set("WWW-Authenticate", "Bearer")
+
+	message := "invalid or missing authentication token"
+	app.errorResponseJSON(w, r, http.StatusUnauthorized, message)
+}
+
+// send an error response if the client must log in first(401)
+func (app *application) authenticationRequiredResponse(w http.ResponseWriter, r *http.Request) {
+	message := "you must be authenticated to access this resource"
+	app.errorResponseJSON(w, r, http.StatusUnauthorized, message)
+}
+
+// send an error response if the account has not been activated yet(403)
+func (app *application) inactiveAccountResponse(w http.ResponseWriter, r *http.Request) {
+	message := "your user account must be activated to access this resource"
+	app.errorResponseJSON(w, r, http.StatusForbidden, message)
+}
+
+// send an error response if the user lacks the permission they need(403)
+func (app *application) notPermittedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "your user account doesn't have the necessary permissions to access this resource"
+	app.errorResponseJSON(w, r, http.StatusForbidden, message)
+}
+
+// send an error response if the If-Match header doesn't match the resource(412)
+func (app *application) preconditionFailedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the resource has changed since you last fetched it, please fetch it again"
+	app.errorResponseJSON(w, r, http.StatusPreconditionFailed, message)
+}
+
+// send an error response if an author still has quotes attributed to them(409)
+func (app *application) authorHasQuotesResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the author still has quotes, move or delete them first"
+	app.errorResponseJSON(w, r, http.StatusConflict, message)
+}
+
+// send an error response if the author's only quotes are in the trash(409)
+func (app *application) authorHasTrashedQuotesResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the author's quotes are in the trash, they have to be restored and moved or purged from the trash first"
+	app.errorResponseJSON(w, r, http.StatusConflict, message)
+}
+
+// send an error response if we can't send the response in any of the
+// formats the client accepts
+func (app *application) notAcceptableResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the requested resource is only available as JSON, compact JSON, NDJSON, CSV, XML or plain text"
+	app.errorResponseJSON(w, r, http.StatusNotAcceptable, message)
+}
+
+// send an error response if the request body is in a format we can't read
+func (app *application) unsupportedMediaTypeResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the Content-Type must be application/json, text/csv or text/plain"
+	app.errorResponseJSON(w, r, http.StatusUnsupportedMediaType, message)
+}
+
+// send a 422 listing the content policy rules the quote breaks
+func (app *application) contentPolicyResponse(w http.ResponseWriter, r *http.Request, violations policy.Violations) {
+	lang := negotiateLanguage(r.Header.Get("Accept-Language"))
+
+	headers := make(http.Header)
+	headers.Set("Content-Language", lang)
+	headers.Add("Vary", "Accept-Language")
+
+	message := envelope{
+		"message":    "this quote breaks the content policy",
+		"violations": violations.RejectedIn(lang),
+	}
+	err := app.writeJSON(w, http.StatusUnprocessableEntity, envelope{"error": message}, headers)
+	if err != nil {
+		app.logError(r, err)
+		w.WriteHeader(500)
+	}
+}
+
+// send a 409 pointing at the quote that the client's quote duplicates. A
+// quote the client isn't allowed to see (e.g. somebody else's waiting for
+// a moderator) isn't pointed at, that would give away that it exists
+func (app *application) duplicateQuoteResponse(w http.ResponseWriter, r *http.Request, quote *data.Quote) {
+	id, err := app.quoteModel.GetDuplicateID(quote)
+	if err != nil {
+		// the other quote was changed or deleted in the meantime
+		if errors.Is(err, data.ErrRecordNotFound) {
+			app.editConflictResponse(w, r)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	visible, err := app.canViewQuoteID(r, id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	message := envelope{
+		"message": "this quote has already been added",
+	}
+	if visible {
+		message["duplicate_of"] = id
+		message["location"] = fmt.Sprintf("/v1/quotes/%d", id)
+	}
+	app.errorResponseJSON(w, r, http.StatusConflict, message)
+}
@@ -0,0 +1,199 @@
+// Filename: cmd/api/exports.go
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aiycoleman/qod/internal/data"
+	"github.com/aiycoleman/qod/internal/validator"
+)
+
+// Send every quote matching the same filters as the quotes list, written
+// out as they are read from the database so the export never has to fit
+// in memory. JSON (the default), NDJSON and CSV are supported
+func (app *application) exportQuotesHandler(w http.ResponseWriter, r *http.Request) {
+	queryParameters := r.URL.Query()
+
+	v := validator.New()
+
+	criteria := app.readQuoteCriteria(queryParameters, 0, v)
+
+	// exports aren't paged, only the sort matters
+	filters := data.Filters{
+		Page:         1,
+		PageSize:     1,
+		Sort:         app.getSingleQueryParameter(queryParameters, "sort", "id"),
+		SortSafeList: quoteSortSafeList,
+	}
+
+	// only the formats that can be written one quote at a time
+	format, ok := app.negotiateFormat(r, v, formatJSON, formatNDJSON, formatCSV)
+	if !ok {
+		app.notAcceptableResponse(w, r)
+		return
+	}
+
+	data.ValidateFilters(v, filters)
+	if !v.IsEmpty() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	// the extension helps when the download is saved
+	extensions := map[string]string{formatJSON: "json", formatNDJSON: "ndjson", formatCSV: "csv"}
+	filename := fmt.Sprintf("quotes-%s.%s", time.Now().UTC().Format(data.DayLayout), extensions[format])
+
+	writer := newQuoteStreamWriter(w, format)
+	started := false
+
+	// the status and headers can't change once something is written, so
+	// they are only sent once the first quote has been read
+	start := func() error {
+		started = true
+
+		// the server's write timeout is meant for normal responses
+		err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(5 * time.Minute))
+		if err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+
+		w.Header().Set("Content-Type", formatContentType(format))
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		w.Header().Add("Vary", "Accept")
+		w.WriteHeader(http.StatusOK)
+
+		return writer.begin()
+	}
+
+	err := app.quoteModel.Export(criteria, filters, func(quote *data.Quote) error {
+		if !started {
+			err := start()
+			if err != nil {
+				return err
+			}
+		}
+		return writer.write(quote)
+	})
+	if err != nil {
+		if !started {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		// too late for an error response, the client gets a cut off file
+		app.logError(r, err)
+		return
+	}
+
+	// an export with no quotes in it
+	if !started {
+		err = start()
+		if err != nil {
+			app.logError(r, err)
+			return
+		}
+	}
+
+	err = writer.end()
+	if err != nil {
+		app.logError(r, err)
+	}
+}
+
+// Writes quotes one at a time in an export format, flushing every so often
+// so the client receives them while the export runs
+type quoteStreamWriter struct {
+	w          io.Writer
+	controller *http.ResponseController
+	format     string
+	csv        *csv.Writer
+	count      int
+}
+
+func newQuoteStreamWriter(w http.ResponseWriter, format string) *quoteStreamWriter {
+	return &quoteStreamWriter{w: w, controller: http.NewResponseController(w), format: format, csv: csv.NewWriter(w)}
+}
+
+// Write whatever comes before the first quote
+func (sw *quoteStreamWriter) begin() error {
+	switch sw.format {
+	case formatJSON:
+		_, err := io.WriteString(sw.w, "{\"quotes\":[\n")
+		return err
+	case formatCSV:
+		return sw.csv.Write(data.QuoteFieldsSafeList)
+	}
+	return nil
+}
+
+func (sw *quoteStreamWriter) write(quote *data.Quote) error {
+	var err error
+
+	switch sw.format {
+	case formatCSV:
+		var rows []map[string]json.RawMessage
+		rows, err = toRecords(quote)
+		if err != nil {
+			return err
+		}
+		line := make([]string, len(data.QuoteFieldsSafeList))
+		for i, column := range data.QuoteFieldsSafeList {
+			line[i] = flattenValue(rows[0][column])
+		}
+		err = sw.csv.Write(line)
+
+	default:
+		var js []byte
+		js, err = json.Marshal(quote)
+		if err != nil {
+			return err
+		}
+		// a JSON array needs commas between the quotes
+		if sw.format == formatJSON && sw.count > 0 {
+			js = append([]byte(",\n"), js...)
+		}
+		if sw.format == formatNDJSON {
+			js = append(js, '\n')
+		}
+		_, err = sw.w.Write(js)
+	}
+	if err != nil {
+		return err
+	}
+
+	sw.count++
+	if sw.count%100 == 0 {
+		return sw.flush()
+	}
+	return nil
+}
+
+// Write whatever comes after the last quote
+func (sw *quoteStreamWriter) end() error {
+	if sw.format == formatJSON {
+		_, err := io.WriteString(sw.w, "\n]}\n")
+		if err != nil {
+			return err
+		}
+	}
+	return sw.flush()
+}
+
+func (sw *quoteStreamWriter) flush() error {
+	sw.csv.Flush()
+	err := sw.csv.Error()
+	if err != nil {
+		return err
+	}
+	// a response writer that can't flush just sends everything at the end
+	err = sw.controller.Flush()
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+// Filename: cmd/api/fieldsets.go
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"slices"
+
+	"github.com/aiycoleman/qod/internal/data"
+	"github.com/aiycoleman/qod/internal/validator"
+)
+
+// Read ?fields=id,content and ?include=author,tags, checking them against
+// the resource's allow-lists
+func (app *application) readFieldset(queryParameters url.Values, fieldsSafeList []string, includeSafeList []string, v *validator.Validator) data.Fieldset {
+	fieldset := data.Fieldset{
+		Fields:          app.getMultipleQueryParameters(queryParameters, "fields", []string{}),
+		Include:         app.getMultipleQueryParameters(queryParameters, "include", []string{}),
+		FieldsSafeList:  fieldsSafeList,
+		IncludeSafeList: includeSafeList,
+	}
+
+	data.ValidateFieldset(v, fieldset)
+	return fieldset
+}
+
+// Turn a record into a map of its JSON fields, keeping only the chosen
+// fields. No fields means all of them
+func pickFields(record any, fields []string) (map[string]any, error) {
+	js, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+
+	// raw values so the fields are written back exactly as they were
+	var all map[string]json.RawMessage
+	err = json.Unmarshal(js, &all)
+	if err != nil {
+		return nil, err
+	}
+
+	picked := make(map[string]any, len(all))
+	for name, value := range all {
+		if len(fields) == 0 || slices.Contains(fields, name) {
+			picked[name] = value
+		}
+	}
+	return picked, nil
+}
+
+// Apply a fieldset to a list of records that have nothing to embed
+func pickAllFields[T any](records []T, fields []string) (any, error) {
+	if len(fields) == 0 {
+		return records, nil
+	}
+
+	shaped := make([]map[string]any, 0, len(records))
+	for _, record := range records {
+		picked, err := pickFields(record, fields)
+		if err != nil {
+			return nil, err
+		}
+		shaped = append(shaped, picked)
+	}
+	return shaped, nil
+}
+
+// Apply a fieldset to a list of quotes. Related records are looked up for
+// the whole list at once and are sent even if their field was not picked
+func (app *application) shapeQuotes(quotes []*data.Quote, fieldset data.Fieldset) (any, error) {
+	if fieldset.IsEmpty() {
+		return quotes, nil
+	}
+
+	var authors map[int64]*data.Author
+	if fieldset.Includes("author") {
+		var ids []int64
+		for _, quote := range quotes {
+			ids = append(ids, quote.AuthorID)
+		}
+
+		var err error
+		authors, err = app.authorModel.GetMany(ids)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var tagCounts map[string]int
+	if fieldset.Includes("tags") {
+		var tags []string
+		for _, quote := range quotes {
+			tags = append(tags, quote.Tags...)
+		}
+
+		var err error
+		tagCounts, err = app.tagModel.GetCounts(tags)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	shaped := make([]map[string]any, 0, len(quotes))
+	for _, quote := range quotes {
+		picked, err := pickFields(quote, fieldset.Fields)
+		if err != nil {
+			return nil, err
+		}
+
+		if authors != nil {
+			picked["author"] = authors[quote.AuthorID]
+		}
+		if tagCounts != nil {
+			tags := make([]data.TagCount, 0, len(quote.Tags))
+			for _, tag := range quote.Tags {
+				tags = append(tags, data.TagCount{Tag: tag, Count: tagCounts[tag]})
+			}
+			picked["tags"] = tags
+		}
+
+		shaped = append(shaped, picked)
+	}
+	return shaped, nil
+}
+
+// Apply a fieldset to a single quote
+func (app *application) shapeQuote(quote *data.Quote, fieldset data.Fieldset) (any, error) {
+	if fieldset.IsEmpty() {
+		return quote, nil
+	}
+
+	shaped, err := app.shapeQuotes([]*data.Quote{quote}, fieldset)
+	if err != nil {
+		return nil, err
+	}
+	return shaped.([]map[string]any)[0], nil
+}
+
+// The order of the quote fields in formats that have columns
+func quoteColumns(fieldset data.Fieldset) []string {
+	if len(fieldset.Fields) > 0 {
+		return fieldset.Fields
+	}
+	return data.QuoteFieldsSafeList
+}
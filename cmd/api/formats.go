@@ -0,0 +1,479 @@
+// Filename: cmd/api/formats.go
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aiycoleman/qod/internal/data"
+	"github.com/aiycoleman/qod/internal/validator"
+)
+
+// The formats a response can be written in. The names are the values
+// accepted by ?format=
+const (
+	formatJSON        = "json"
+	formatCompactJSON = "compact"
+	formatCSV         = "csv"
+	formatXML         = "xml"
+	formatText        = "text"
+	formatNDJSON      = "ndjson"
+)
+
+// The formats in the order we prefer them when the client likes several
+// equally. Compact JSON is asked for with Accept: application/json; compact=true
+var responseFormats = []struct {
+	name        string
+	mediaType   string
+	compact     bool
+	contentType string
+}{
+	{formatJSON, "application/json", false, "application/json"},
+	{formatCompactJSON, "application/json", true, "application/json"},
+	{formatNDJSON, "application/x-ndjson", false, "application/x-ndjson"},
+	{formatCSV, "text/csv", false, "text/csv; charset=utf-8"},
+	{formatXML, "application/xml", false, "application/xml; charset=utf-8"},
+	{formatText, "text/plain", false, "text/plain; charset=utf-8"},
+}
+
+// One entry of the Accept header, e.g. text/csv;q=0.5
+type mediaRange struct {
+	mediaType string
+	compact   bool
+	q         float64
+}
+
+// Work out the response format from ?format= or else the Accept header,
+// choosing from the given formats or from all of them when none are given.
+// An unknown ?format= is a validation error, ok is false when nothing in
+// the Accept header can be sent
+func (app *application) negotiateFormat(r *http.Request, v *validator.Validator, allowed ...string) (format string, ok bool) {
+	var names []string
+	for _, f := range responseFormats {
+		if len(allowed) == 0 || slices.Contains(allowed, f.name) {
+			names = append(names, f.name)
+		}
+	}
+
+	format = app.getSingleQueryParameter(r.URL.Query(), "format", "")
+	if format != "" {
+		v.Check(validator.PermittedValue(format, names...), "format", validator.NewError("not_permitted", "values", strings.Join(names, ", ")))
+		return format, true
+	}
+
+	accept := r.Header.Get("Accept")
+	if strings.TrimSpace(accept) == "" {
+		return names[0], true
+	}
+
+	ranges := parseAccept(accept)
+
+	bestQ := 0.0
+	for _, f := range responseFormats {
+		if !slices.Contains(names, f.name) {
+			continue
+		}
+
+		// the most specific range that matches decides the q value
+		q, specificity := 0.0, 0
+		for _, mr := range ranges {
+			s := mr.matches(f.mediaType, f.compact)
+			if s > specificity {
+				q, specificity = mr.q, s
+			}
+		}
+		if q > bestQ {
+			format, bestQ = f.name, q
+		}
+	}
+
+	return format, format != ""
+}
+
+// Split the Accept header into media ranges, skipping any we can't parse
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		mr := mediaRange{mediaType: mediaType, compact: params["compact"] == "true", q: 1}
+		if qValue, found := params["q"]; found {
+			q, err := strconv.ParseFloat(qValue, 64)
+			if err != nil || q < 0 || q > 1 {
+				continue
+			}
+			mr.q = q
+		}
+		ranges = append(ranges, mr)
+	}
+
+	return ranges
+}
+
+// How specifically the range matches a media type, 0 means it doesn't.
+// Only an explicit application/json; compact=true matches compact JSON
+func (mr mediaRange) matches(mediaType string, compact bool) int {
+	if mr.compact != compact {
+		return 0
+	}
+
+	switch {
+	case mr.mediaType == mediaType:
+		return 3
+	case compact:
+		return 0
+	case mr.mediaType == strings.Split(mediaType, "/")[0]+"/*":
+		return 2
+	case mr.mediaType == "*/*":
+		return 1
+	}
+	return 0
+}
+
+// Look up the Content-Type header for a format
+func formatContentType(format string) string {
+	for _, f := range responseFormats {
+		if f.name == format {
+			return f.contentType
+		}
+	}
+	return "application/json"
+}
+
+// Write the response in the negotiated format. The JSON formats send the
+// envelope as usual. The others send the records held under the key
+// records (a single record or a list), with their fields in the order of
+// columns, and put the @metadata into headers
+func (app *application) writeResponse(w http.ResponseWriter, status int, format string, data envelope, records string, columns []string, headers http.Header) error {
+	// caches must keep each format separately
+	w.Header().Add("Vary", "Accept")
+
+	if format == formatJSON {
+		return app.writeJSON(w, status, data, headers)
+	}
+
+	var body bytes.Buffer
+
+	switch format {
+	case formatCompactJSON:
+		err := json.NewEncoder(&body).Encode(data)
+		if err != nil {
+			return err
+		}
+
+	case formatXML:
+		err := writeXML(&body, data, columns)
+		if err != nil {
+			return err
+		}
+
+	case formatNDJSON:
+		// each record is encoded and sent on its own so the list is
+		// never held as one big body
+		return app.writeNDJSON(w, status, data[records], data, headers)
+
+	default:
+		rows, err := toRecords(data[records])
+		if err != nil {
+			return err
+		}
+
+		switch format {
+		case formatCSV:
+			err = writeCSV(&body, rows, columns)
+		case formatText:
+			err = writeFortunes(&body, rows)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for key, value := range headers {
+		w.Header()[key] = value
+	}
+	setMetadataHeaders(w, data)
+	w.Header().Set("Content-Type", formatContentType(format))
+
+	w.WriteHeader(status)
+	_, err := w.Write(body.Bytes())
+	return err
+}
+
+// Turn a record or list of records into maps of their JSON fields
+func toRecords(value any) ([]map[string]json.RawMessage, error) {
+	js, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []map[string]json.RawMessage
+	if bytes.HasPrefix(bytes.TrimSpace(js), []byte("[")) {
+		err = json.Unmarshal(js, &rows)
+		return rows, err
+	}
+
+	var row map[string]json.RawMessage
+	err = json.Unmarshal(js, &row)
+	return []map[string]json.RawMessage{row}, err
+}
+
+// The metadata doesn't fit in the other formats' bodies so the client gets
+// it as headers instead
+func setMetadataHeaders(w http.ResponseWriter, response envelope) {
+	metadata, ok := response["@metadata"].(data.Metadata)
+	if !ok {
+		return
+	}
+
+	if metadata.TotalRecords > 0 {
+		w.Header().Set("X-Total-Records", strconv.Itoa(metadata.TotalRecords))
+		w.Header().Set("X-Current-Page", strconv.Itoa(metadata.CurrentPage))
+		w.Header().Set("X-Last-Page", strconv.Itoa(metadata.LastPage))
+	}
+	if metadata.NextCursor != "" {
+		w.Header().Set("X-Next-Cursor", metadata.NextCursor)
+	}
+}
+
+// A header row, then one row per record. Lists of plain values are joined
+// with commas and anything nested is written as JSON
+func writeCSV(body *bytes.Buffer, rows []map[string]json.RawMessage, columns []string) error {
+	columns = presentColumns(rows, columns)
+
+	cw := csv.NewWriter(body)
+	err := cw.Write(columns)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		line := make([]string, len(columns))
+		for i, column := range columns {
+			line[i] = flattenValue(row[column])
+		}
+		err = cw.Write(line)
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// Write the quotes the way the fortune program stores them, each one
+// followed by a line holding just %
+//
+//	Stay hungry, stay foolish.
+//	    -- Steve Jobs
+//	%
+func writeFortunes(body *bytes.Buffer, rows []map[string]json.RawMessage) error {
+	for _, row := range rows {
+		content := flattenValue(row["content"])
+
+		// the author is an object when ?include=author was used
+		var author struct {
+			Name string `json:"name"`
+		}
+		authorName := flattenValue(row["author"])
+		if json.Unmarshal(row["author"], &author) == nil && author.Name != "" {
+			authorName = author.Name
+		}
+
+		if content != "" {
+			fmt.Fprintln(body, content)
+		}
+		if authorName != "" {
+			fmt.Fprintf(body, "    -- %s\n", authorName)
+		}
+		fmt.Fprintln(body, "%")
+	}
+	return nil
+}
+
+// One JSON record per line, each one encoded straight from the record (or
+// list of records) and flushed as it is written
+func (app *application) writeNDJSON(w http.ResponseWriter, status int, records any, data envelope, headers http.Header) error {
+	for key, value := range headers {
+		w.Header()[key] = value
+	}
+	setMetadataHeaders(w, data)
+	w.Header().Set("Content-Type", formatContentType(formatNDJSON))
+	w.WriteHeader(status)
+
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+
+	list := reflect.ValueOf(records)
+	if list.Kind() != reflect.Slice {
+		return encoder.Encode(records)
+	}
+
+	for i := range list.Len() {
+		err := encoder.Encode(list.Index(i).Interface())
+		if err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	return nil
+}
+
+// Write the envelope as XML inside a <response> element. Lists get one
+// child per value named after the list without its s, e.g. <tags><tag>
+func writeXML(body *bytes.Buffer, data envelope, columns []string) error {
+	js, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(js))
+	decoder.UseNumber()
+	var value any
+	err = decoder.Decode(&value)
+	if err != nil {
+		return err
+	}
+
+	body.WriteString(xml.Header)
+	encoder := xml.NewEncoder(body)
+	encoder.Indent("", "\t")
+
+	err = encodeXMLValue(encoder, "response", value, columns)
+	if err != nil {
+		return err
+	}
+	err = encoder.Flush()
+	body.WriteString("\n")
+	return err
+}
+
+func encodeXMLValue(encoder *xml.Encoder, name string, value any, columns []string) error {
+	// @metadata isn't a valid element name
+	start := xml.StartElement{Name: xml.Name{Local: strings.TrimPrefix(name, "@")}}
+
+	err := encoder.EncodeToken(start)
+	if err != nil {
+		return err
+	}
+
+	switch value := value.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sortColumns(keys, columns)
+
+		for _, key := range keys {
+			err = encodeXMLValue(encoder, key, value[key], columns)
+			if err != nil {
+				return err
+			}
+		}
+
+	case []any:
+		childName := strings.TrimSuffix(start.Name.Local, "s")
+		if childName == start.Name.Local {
+			childName = "item"
+		}
+
+		for _, child := range value {
+			err = encodeXMLValue(encoder, childName, child, columns)
+			if err != nil {
+				return err
+			}
+		}
+
+	case nil:
+		// an empty element
+
+	default:
+		err = encoder.EncodeToken(xml.CharData(fmt.Sprint(value)))
+		if err != nil {
+			return err
+		}
+	}
+
+	return encoder.EncodeToken(start.End())
+}
+
+// Keep the columns that at least one record has, adding any others at the
+// end in alphabetical order
+func presentColumns(rows []map[string]json.RawMessage, columns []string) []string {
+	var present []string
+	for _, row := range rows {
+		for key := range row {
+			if !slices.Contains(present, key) {
+				present = append(present, key)
+			}
+		}
+	}
+
+	sortColumns(present, columns)
+	return present
+}
+
+// Sort keys by their place in columns, with the rest alphabetically after
+func sortColumns(keys []string, columns []string) {
+	sort.SliceStable(keys, func(i, j int) bool {
+		a, b := slices.Index(columns, keys[i]), slices.Index(columns, keys[j])
+		switch {
+		case a == -1 && b == -1:
+			return keys[i] < keys[j]
+		case a == -1:
+			return false
+		case b == -1:
+			return true
+		}
+		return a < b
+	})
+}
+
+// Turn a JSON value into a single piece of text
+func flattenValue(raw json.RawMessage) string {
+	var value any
+	if json.Unmarshal(raw, &value) != nil {
+		return ""
+	}
+
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case []any:
+		var parts []string
+		for _, part := range value {
+			text, ok := part.(string)
+			if !ok {
+				// not a list of plain values
+				return string(raw)
+			}
+			parts = append(parts, text)
+		}
+		return strings.Join(parts, ",")
+	case map[string]any:
+		return string(raw)
+	}
+
+	// numbers and booleans are written as they are
+	return string(raw)
+}
@@ -0,0 +1,41 @@
+// Filename: cmd/api/formats_test.go
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aiycoleman/qod/internal/validator"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	app := &application{}
+
+	tests := []struct {
+		target string
+		accept string
+		want   string
+		ok     bool
+	}{
+		{"/v1/quotes", "", formatJSON, true},
+		{"/v1/quotes", "*/*", formatJSON, true},
+		{"/v1/quotes", "text/csv", formatCSV, true},
+		{"/v1/quotes", "application/json;q=0.5, application/xml", formatXML, true},
+		{"/v1/quotes", "application/json; compact=true", formatCompactJSON, true},
+		{"/v1/quotes", "text/plain, */*;q=0.1", formatText, true},
+		{"/v1/quotes", "image/png", "", false},
+		{"/v1/quotes?format=ndjson", "image/png", formatNDJSON, true},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", tt.target, nil)
+		if tt.accept != "" {
+			r.Header.Set("Accept", tt.accept)
+		}
+
+		got, ok := app.negotiateFormat(r, validator.New())
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("%s with Accept %q: expected %q %v, got %q %v", tt.target, tt.accept, tt.want, tt.ok, got, ok)
+		}
+	}
+}
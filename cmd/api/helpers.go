@@ -9,7 +9,9 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/aiycoleman/qod/internal/data"
 	"github.com/aiycoleman/qod/internal/validator"
 	"github.com/julienschmidt/httprouter"
 )
@@ -112,6 +114,43 @@ func (app *application) readIDParam(r *http.Request) (int64, error) {
 	return id, nil
 }
 
+// Get the revision version number from the URL
+func (app *application) readVersionParam(r *http.Request) (int32, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	version, err := strconv.ParseInt(params.ByName("version"), 10, 32)
+	if err != nil || version < 1 {
+		return 0, errors.New("invalid version parameter")
+	}
+	return int32(version), nil
+}
+
+// Get the date (YYYY-MM-DD) from the URL
+func (app *application) readDateParam(r *http.Request) (time.Time, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	day, err := time.Parse(data.DayLayout, params.ByName("date"))
+	if err != nil {
+		return time.Time{}, errors.New("invalid date parameter")
+	}
+	return day, nil
+}
+
+// httprouter does not allow a fixed segment such as /v1/quotes/today next to
+// a wildcard such as /v1/quotes/:id, so the wildcard route checks for the
+// fixed names itself and hands the request to the matching handler
+func (app *application) withFixedSegments(param string, fixed map[string]http.HandlerFunc, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := httprouter.ParamsFromContext(r.Context())
+		handler, found := fixed[params.ByName(param)]
+		if found {
+			handler(w, r)
+			return
+		}
+		next(w, r)
+	}
+}
+
 func (app *application) getSingleQueryParameter(queryParameters url.Values, key string, defaultValue string) string {
 	// url.Values is a key:value hash map of the query parameters
 	result := queryParameters.Get(key)
@@ -141,9 +180,47 @@ func (app *application) getSingleIntegerParameter(queryParameters url.Values, ke
 	// try to convert to an integer
 	intValue, err := strconv.Atoi(result)
 	if err != nil {
-		v.AddError(key, "must be an integer value")
+		v.AddError(key, validator.NewError("invalid_integer"))
 		return defaultValue
 	}
 
 	return intValue
 }
+
+// this method accepts either a full RFC3339 timestamp or just a date
+// (YYYY-MM-DD, meaning midnight UTC) and returns nil when the key is missing
+func (app *application) getSingleTimeParameter(queryParameters url.Values, key string, v *validator.Validator) *time.Time {
+	result := queryParameters.Get(key)
+	if result == "" {
+		return nil
+	}
+
+	for _, layout := range []string{time.RFC3339, data.DayLayout} {
+		t, err := time.Parse(layout, result)
+		if err == nil {
+			return &t
+		}
+	}
+
+	v.AddError(key, validator.NewError("invalid_time"))
+	return nil
+}
+
+// Run a function in a background goroutine. Panics are recovered and logged
+// and serve() waits for it to finish before shutting down
+func (app *application) background(fn func()) {
+	app.wg.Add(1)
+
+	go func() {
+		defer app.wg.Done()
+		// a panic here would not be caught by recoverPanic
+		defer func() {
+			err := recover()
+			if err != nil {
+				app.logger.Error(fmt.Sprintf("%v", err))
+			}
+		}()
+
+		fn()
+	}()
+}
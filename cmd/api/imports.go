@@ -0,0 +1,275 @@
+// Filename: cmd/api/imports.go
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/aiycoleman/qod/internal/data"
+	"github.com/aiycoleman/qod/internal/validator"
+)
+
+// One quote read from an import file
+type importEntry struct {
+	Content string   `json:"content"`
+	Author  string   `json:"author"`
+	Tags    []string `json:"tags"`
+}
+
+// What happened to one entry of an import. Rows are numbered from 1 in the
+// order they appear in the file
+type importRow struct {
+	Row         int                  `json:"row"`
+	Status      string               `json:"status"` // accepted, duplicate or invalid
+	ID          int64                `json:"id,omitempty"`
+	DuplicateOf int64                `json:"duplicate_of,omitempty"`
+	Similar     []*data.SimilarQuote `json:"similar,omitempty"` // possible near duplicates of an accepted quote
+	Errors      map[string]string    `json:"errors,omitempty"`
+}
+
+// Add many quotes at once from a JSON array, a CSV file with content,
+// author and (optionally) tags columns, or a fortune file. Invalid and
+// duplicate entries are skipped and the rest are added together
+func (app *application) importQuotesHandler(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		mediaType = ""
+	}
+
+	var entries []importEntry
+
+	switch mediaType {
+	case "application/json":
+		err = app.readJSON(w, r, &entries)
+	case "text/csv":
+		entries, err = readCSVImport(http.MaxBytesReader(w, r.Body, 256_000))
+	case "text/plain":
+		entries, err = readFortuneImport(http.MaxBytesReader(w, r.Body, 256_000))
+	default:
+		app.unsupportedMediaTypeResponse(w, r)
+		return
+	}
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			err = fmt.Errorf("the body must not be larger than %d bytes", maxBytesError.Limit)
+		}
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(len(entries) > 0, "quotes", validator.NewError("empty"))
+	v.Check(len(entries) <= data.MaxImportSize, "quotes", validator.NewError("max_items", "max", data.MaxImportSize))
+	if !v.IsEmpty() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	// the logged in user is the one submitting the quotes
+	user := app.contextGetUser(r)
+
+	// the quotes aren't shown publicly until a moderator approves them
+	moderator, err := app.isModerator(r)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// row errors are in the client's language like any other validation error
+	lang := negotiateLanguage(r.Header.Get("Accept-Language"))
+
+	rows := make([]importRow, len(entries))
+	var quotes []*data.Quote
+	var quoteRows []int
+
+	for i, entry := range entries {
+		rows[i].Row = i + 1
+
+		quote := &data.Quote{
+			Content:     validator.CleanText(entry.Content),
+			Author:      validator.CleanText(entry.Author),
+			Tags:        data.NormalizeTags(entry.Tags),
+			SubmittedBy: &data.Submitter{ID: user.ID, Username: user.Username},
+		}
+
+		v := validator.New()
+		data.ValidateQuote(v, quote, app.config.limits)
+		if !v.IsEmpty() {
+			rows[i].Status = "invalid"
+			rows[i].Errors, _ = v.Localize(lang)
+			continue
+		}
+
+		violations := app.checkContentPolicy(quote)
+		if violations.Rejected() {
+			violations.AddErrors(v)
+			rows[i].Status = "invalid"
+			rows[i].Errors, _ = v.Localize(lang)
+			continue
+		}
+		setSubmissionStatus(quote, moderator, violations)
+
+		quotes = append(quotes, quote)
+		quoteRows = append(quoteRows, i)
+	}
+
+	if len(quotes) > 0 {
+		results, err := app.quoteModel.Import(quotes, app.config.search.duplicateThreshold)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		for i, quote := range quotes {
+			row := &rows[quoteRows[i]]
+			if results[i].DuplicateOf != 0 {
+				row.Status = "duplicate"
+				// only point at quotes the client is allowed to see
+				visible, err := app.canViewQuoteID(r, results[i].DuplicateOf)
+				if err != nil {
+					app.serverErrorResponse(w, r, err)
+					return
+				}
+				if visible {
+					row.DuplicateOf = results[i].DuplicateOf
+				}
+				continue
+			}
+			row.Status = "accepted"
+			row.ID = quote.ID
+			row.Similar = results[i].Similar
+		}
+	}
+
+	counts := map[string]int{"accepted": 0, "duplicate": 0, "invalid": 0}
+	for _, row := range rows {
+		counts[row.Status]++
+	}
+
+	data := envelope{
+		"import": envelope{
+			"accepted":  counts["accepted"],
+			"duplicate": counts["duplicate"],
+			"invalid":   counts["invalid"],
+			"rows":      rows,
+		},
+	}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Read a CSV import. The first line names the columns, content and author
+// are required and tags (comma-separated) is optional. Other columns are
+// ignored so a CSV export can be imported again
+func readCSVImport(body io.Reader) ([]importEntry, error) {
+	reader := csv.NewReader(body)
+	// rows may leave out trailing empty columns
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("the body must not be empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("the body contains badly-formed CSV: %w", err)
+	}
+
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+	contentColumn := slices.Index(header, "content")
+	authorColumn := slices.Index(header, "author")
+	tagsColumn := slices.Index(header, "tags")
+	if contentColumn == -1 || authorColumn == -1 {
+		return nil, errors.New("the CSV header must have content and author columns")
+	}
+
+	column := func(record []string, i int) string {
+		if i == -1 || i >= len(record) {
+			return ""
+		}
+		return record[i]
+	}
+
+	var entries []importEntry
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("the body contains badly-formed CSV: %w", err)
+		}
+
+		entry := importEntry{
+			Content: column(record, contentColumn),
+			Author:  column(record, authorColumn),
+		}
+		if tags := column(record, tagsColumn); tags != "" {
+			entry.Tags = strings.Split(tags, ",")
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// Read a fortune file, where each quote ends with a line holding just %.
+// A last line starting with -- is the author, and lines of the quote itself
+// are joined with spaces since fortune files wrap long quotes
+//
+//	Stay hungry, stay foolish.
+//	    -- Steve Jobs
+//	%
+func readFortuneImport(body io.Reader) ([]importEntry, error) {
+	var entries []importEntry
+	var lines []string
+
+	// turn the lines collected so far into a quote
+	addEntry := func() {
+		var entry importEntry
+		if len(lines) > 0 {
+			last := strings.TrimSpace(lines[len(lines)-1])
+			if strings.HasPrefix(last, "--") || strings.HasPrefix(last, "—") {
+				entry.Author = strings.TrimSpace(strings.TrimLeft(last, "-—"))
+				lines = lines[:len(lines)-1]
+			}
+		}
+		entry.Content = strings.Join(lines, " ")
+
+		// blank space between quotes is not a quote
+		if entry.Content != "" || entry.Author != "" {
+			entries = append(entries, entry)
+		}
+		lines = nil
+	}
+
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "%":
+			addEntry()
+		case line != "":
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// the last quote doesn't need a % after it
+	addEntry()
+
+	return entries, nil
+}
@@ -0,0 +1,62 @@
+// Filename: cmd/api/imports_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadFortuneImport(t *testing.T) {
+	file := `Stay hungry,
+stay foolish.
+    -- Steve Jobs
+%
+A quote nobody signed
+%
+
+Imagination is more important than knowledge.
+	— Albert Einstein
+`
+
+	entries, err := readFortuneImport(strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got: %d", len(entries))
+	}
+
+	want := importEntry{Content: "Stay hungry, stay foolish.", Author: "Steve Jobs"}
+	if entries[0].Content != want.Content || entries[0].Author != want.Author {
+		t.Errorf("expected: %+v, got: %+v", want, entries[0])
+	}
+	if entries[1].Author != "" {
+		t.Errorf("expected no author, got: %q", entries[1].Author)
+	}
+	if entries[2].Author != "Albert Einstein" {
+		t.Errorf("expected Albert Einstein, got: %q", entries[2].Author)
+	}
+}
+
+func TestReadCSVImport(t *testing.T) {
+	file := "id,content,author,tags\n1,Stay hungry.,Steve Jobs,\"life,work\"\n2,No tags,Anon\n"
+
+	entries, err := readCSVImport(strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got: %d", len(entries))
+	}
+	if entries[0].Author != "Steve Jobs" || len(entries[0].Tags) != 2 {
+		t.Errorf("unexpected first entry: %+v", entries[0])
+	}
+	if entries[1].Tags != nil {
+		t.Errorf("expected no tags, got: %v", entries[1].Tags)
+	}
+
+	_, err = readCSVImport(strings.NewReader("quote,by\nx,y\n"))
+	if err == nil {
+		t.Error("expected an error for missing columns")
+	}
+}
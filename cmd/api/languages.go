@@ -0,0 +1,43 @@
+// Filename: cmd/api/languages.go
+package main
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aiycoleman/qod/internal/validator"
+)
+
+// Pick the language for validation messages from an Accept-Language
+// header, e.g. "es-BZ, es;q=0.9, en;q=0.8". Only the primary language is
+// compared so es-BZ gets the es messages. English is the fallback
+func negotiateLanguage(acceptLanguage string) string {
+	supported := validator.Languages()
+	language, bestQ := validator.DefaultLanguage, 0.0
+
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+
+		q := 1.0
+		if qValue, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			parsed, err := strconv.ParseFloat(qValue, 64)
+			if err != nil || parsed < 0 || parsed > 1 {
+				continue
+			}
+			q = parsed
+		}
+
+		primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if primary == "*" {
+			primary = validator.DefaultLanguage
+		}
+
+		// the first of equally preferred languages wins
+		if q > bestQ && slices.Contains(supported, primary) {
+			language, bestQ = primary, q
+		}
+	}
+
+	return language
+}
@@ -0,0 +1,26 @@
+// Filename: cmd/api/languages_test.go
+package main
+
+import "testing"
+
+func TestNegotiateLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", "en"},
+		{"es", "es"},
+		{"es-BZ, en;q=0.8", "es"},
+		{"en;q=0.5, es;q=0.9", "es"},
+		{"fr, es;q=0.7", "es"},
+		{"fr", "en"},
+		{"*", "en"},
+		{"es;q=abc, en", "en"},
+	}
+
+	for _, tt := range tests {
+		if got := negotiateLanguage(tt.header); got != tt.want {
+			t.Errorf("negotiateLanguage(%q): expected %q, got %q", tt.header, tt.want, got)
+		}
+	}
+}
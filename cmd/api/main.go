@@ -6,12 +6,16 @@ import (
 	"context"
 	"database/sql"
 	"flag"
+	"fmt"
 	"log/slog"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aiycoleman/qod/internal/data"
+	"github.com/aiycoleman/qod/internal/mailer"
+	"github.com/aiycoleman/qod/internal/policy"
 	_ "github.com/lib/pq"
 )
 
@@ -32,15 +36,55 @@ type configuration struct {
 	cors struct {
 		trustedOrigins []string
 	}
+	daily struct {
+		window int // days before a quote can be the daily quote again
+	}
+	trash struct {
+		retention time.Duration // how long deleted quotes can be restored
+	}
+	search struct {
+		fuzzyThreshold     float64 // how similar (0 to 1) a fuzzy match must be
+		duplicateThreshold float64 // how similar (0 to 1) a new quote must be to another to get a warning
+	}
+	limits data.TextLimits // how long text fields can be, in characters
+	policy struct {
+		words       []string // blocked words and phrases
+		wordsAction string   // what happens to quotes with blocked words (reject|moderate|off)
+		links       string   // what happens to quotes with links
+		caps        string   // what happens to quotes written in capitals
+		repeats     string   // what happens to quotes repeating a character or word
+		maxRepeat   int      // how many times in a row a character or word may appear
+	}
+	mailer struct {
+		backend string // smtp or maildir
+		dir     string // where the maildir backend drops messages
+		sender  string // the From address
+	}
+	smtp struct {
+		host     string
+		port     int
+		username string
+		password string
+	}
 }
 
 // Hold dependencies shared across handlers,
 // such as config and logger.
 type application struct {
-	config     configuration
-	logger     *slog.Logger
-	quoteModel data.QuoteModel
-	userModel  data.UserModel
+	config          configuration
+	logger          *slog.Logger
+	quoteModel      data.QuoteModel
+	userModel       data.UserModel
+	dailyQuoteModel data.DailyQuoteModel
+	tokenModel      data.TokenModel
+	permissionModel data.PermissionModel
+	tagModel        data.TagModel
+	authorModel     data.AuthorModel
+	revisionModel   data.RevisionModel
+	mailer          *mailer.Mailer
+	contentPolicy   *policy.ContentPolicy
+	views           viewCounter    // quote views waiting to be saved
+	wg              sync.WaitGroup // tracks background goroutines
 }
 
 // loadConfig reads configuration from command line flags
@@ -61,6 +105,40 @@ func loadConfig() configuration {
 
 	flag.BoolVar(&cfg.limiter.enabled, "limiter-enabled", true, "Enable rate limiter")
 
+	flag.IntVar(&cfg.daily.window, "daily-window", 30, "Days before a quote can be repeated as the daily quote")
+
+	flag.DurationVar(&cfg.trash.retention, "trash-retention", 30*24*time.Hour, "How long deleted quotes are kept in the trash")
+
+	flag.Float64Var(&cfg.search.fuzzyThreshold, "fuzzy-threshold", 0.4, "Minimum trigram similarity (0-1) for fuzzy matches")
+	flag.Float64Var(&cfg.search.duplicateThreshold, "duplicate-threshold", 0.6, "Minimum trigram similarity (0-1) for near-duplicate warnings")
+
+	// Text limits, counted in characters rather than bytes
+	flag.IntVar(&cfg.limits.Content, "limit-content", data.DefaultTextLimits.Content, "Maximum characters in a quote")
+	flag.IntVar(&cfg.limits.Author, "limit-author", data.DefaultTextLimits.Author, "Maximum characters in an author name")
+	flag.IntVar(&cfg.limits.Bio, "limit-bio", data.DefaultTextLimits.Bio, "Maximum characters in an author bio")
+	flag.IntVar(&cfg.limits.Username, "limit-username", data.DefaultTextLimits.Username, "Maximum characters in a username")
+
+	// Content policy settings
+	flag.Func("policy-words", "Blocked words and phrases (comma separated)",
+		func(val string) error {
+			cfg.policy.words = strings.Split(val, ",")
+			return nil
+		})
+	flag.StringVar(&cfg.policy.wordsAction, "policy-words-action", "reject", "What to do with quotes containing blocked words (reject|moderate|off)")
+	flag.StringVar(&cfg.policy.links, "policy-links", "moderate", "What to do with quotes containing links (reject|moderate|off)")
+	flag.StringVar(&cfg.policy.caps, "policy-caps", "moderate", "What to do with quotes written in capitals (reject|moderate|off)")
+	flag.StringVar(&cfg.policy.repeats, "policy-repeats", "moderate", "What to do with quotes repeating a character or word (reject|moderate|off)")
+	flag.IntVar(&cfg.policy.maxRepeat, "policy-max-repeat", 4, "How many times in a row a character or word may appear")
+
+	// Mailer settings
+	flag.StringVar(&cfg.mailer.backend, "mailer-backend", "maildir", "Mailer backend (smtp|maildir)")
+	flag.StringVar(&cfg.mailer.dir, "mailer-dir", "./tmp/maildir", "Maildir used by the maildir mailer backend")
+	flag.StringVar(&cfg.mailer.sender, "mailer-sender", "Quote of the Day <no-reply@qod.example.com>", "Mailer sender address")
+	flag.StringVar(&cfg.smtp.host, "smtp-host", "localhost", "SMTP host")
+	flag.IntVar(&cfg.smtp.port, "smtp-port", 25, "SMTP port")
+	flag.StringVar(&cfg.smtp.username, "smtp-username", "", "SMTP username")
+	flag.StringVar(&cfg.smtp.password, "smtp-password", "", "SMTP password")
+
 	// Allow us to access space-seperted origins.
 	flag.Func("cors-trusted-origins", "Trusted CORS origins (space seperated)",
 		func(val string) error {
@@ -73,6 +151,41 @@ func loadConfig() configuration {
 	return cfg
 }
 
+// validateConfig rejects settings the API can't work with
+func validateConfig(settings configuration) error {
+	thresholds := map[string]float64{
+		"fuzzy-threshold":     settings.search.fuzzyThreshold,
+		"duplicate-threshold": settings.search.duplicateThreshold,
+	}
+	for name, threshold := range thresholds {
+		if threshold < 0 || threshold > 1 {
+			return fmt.Errorf("-%s must be between 0 and 1, got %v", name, threshold)
+		}
+	}
+
+	// a limit of 0 would reject every quote, author or username
+	limits := map[string]int{
+		"limit-content":  settings.limits.Content,
+		"limit-author":   settings.limits.Author,
+		"limit-bio":      settings.limits.Bio,
+		"limit-username": settings.limits.Username,
+		// 0 would reject every quote too, it has at least one character
+		"policy-max-repeat": settings.policy.maxRepeat,
+	}
+	for name, limit := range limits {
+		if limit < 1 {
+			return fmt.Errorf("-%s must be at least 1, got %d", name, limit)
+		}
+	}
+
+	// a retention of 0 or less would purge quotes the moment they are deleted
+	if settings.trash.retention <= 0 {
+		return fmt.Errorf("-trash-retention must be greater than 0, got %v", settings.trash.retention)
+	}
+
+	return nil
+}
+
 // setupLogger configures the application logger based on environment
 func setupLogger() *slog.Logger {
 	var logger *slog.Logger
@@ -105,6 +218,60 @@ func openDB(settings configuration) (*sql.DB, error) {
 
 }
 
+// setupMailer picks the mail backend from the configuration
+func setupMailer(settings configuration) (*mailer.Mailer, error) {
+	var sender mailer.Sender
+
+	switch settings.mailer.backend {
+	case "smtp":
+		sender = mailer.NewSMTPSender(settings.smtp.host, settings.smtp.port,
+			settings.smtp.username, settings.smtp.password)
+	case "maildir":
+		maildir, err := mailer.NewMaildirSender(settings.mailer.dir)
+		if err != nil {
+			return nil, err
+		}
+		sender = maildir
+	default:
+		return nil, fmt.Errorf("unknown mailer backend %q", settings.mailer.backend)
+	}
+
+	return mailer.New(sender, settings.mailer.sender), nil
+}
+
+// setupContentPolicy builds the rules submitted quotes are checked against
+func setupContentPolicy(settings configuration) (*policy.ContentPolicy, error) {
+	contentPolicy := policy.New()
+
+	rules := []struct {
+		setting string
+		rules   []policy.Rule
+	}{
+		{settings.policy.wordsAction, []policy.Rule{policy.NewWordList(settings.policy.words)}},
+		{settings.policy.links, []policy.Rule{policy.Links{}}},
+		{settings.policy.caps, []policy.Rule{policy.AllCaps{MinLetters: 10, Ratio: 0.8}}},
+		{settings.policy.repeats, []policy.Rule{
+			policy.RepeatedCharacters{Max: settings.policy.maxRepeat},
+			policy.RepeatedWords{Max: settings.policy.maxRepeat},
+		}},
+	}
+
+	for _, r := range rules {
+		action, enabled, err := policy.ParseAction(r.setting)
+		if err != nil {
+			return nil, err
+		}
+		if !enabled {
+			continue
+		}
+		for _, rule := range r.rules {
+			contentPolicy.Add(rule, action)
+		}
+	}
+
+	return contentPolicy, nil
+}
+
 // printUB is a small test function, not used in production (testing).
 func printUB() string {
 	return "Hello, UB!"
@@ -119,6 +286,12 @@ func main() {
 	// Initialize logger
 	logger := setupLogger()
 
+	err := validateConfig(cfg)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
 	// Call to openDB() sets up our connection pool
 	db, err := openDB(cfg)
 	if err != nil {
@@ -130,12 +303,32 @@ func main() {
 
 	logger.Info("database connection pool established")
 
+	emailer, err := setupMailer(cfg)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	contentPolicy, err := setupContentPolicy(cfg)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
 	// Initialize application struc with dependencies
 	app := &application{
-		config:     cfg,
-		logger:     logger,
-		quoteModel: data.QuoteModel{DB: db},
-		userModel:  data.UserModel{DB: db},
+		config:          cfg,
+		logger:          logger,
+		quoteModel:      data.QuoteModel{DB: db},
+		userModel:       data.UserModel{DB: db},
+		dailyQuoteModel: data.DailyQuoteModel{DB: db},
+		tokenModel:      data.TokenModel{DB: db},
+		permissionModel: data.PermissionModel{DB: db},
+		tagModel:        data.TagModel{DB: db},
+		authorModel:     data.AuthorModel{DB: db},
+		revisionModel:   data.RevisionModel{DB: db},
+		mailer:          emailer,
+		contentPolicy:   contentPolicy,
 	}
 
 	// Run the application
@@ -0,0 +1,132 @@
+// Filename: cmd/api/moderation.go
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/aiycoleman/qod/internal/data"
+	"github.com/aiycoleman/qod/internal/validator"
+)
+
+// List the quotes waiting for a moderator, oldest first. ?status= shows the
+// approved or rejected quotes instead, and the usual quote filters work too
+func (app *application) listModerationQueueHandler(w http.ResponseWriter, r *http.Request) {
+	queryParameters := r.URL.Query()
+
+	v := validator.New()
+
+	criteria := app.readQuoteCriteria(queryParameters, 0, v)
+	criteria.Status = app.getSingleQueryParameter(queryParameters, "status", data.StatusPending)
+	v.Check(validator.PermittedValue(criteria.Status, data.QuoteStatuses...), "status", validator.NewError("not_permitted", "values", strings.Join(data.QuoteStatuses, ", ")))
+
+	filters := data.Filters{
+		Page:         app.getSingleIntegerParameter(queryParameters, "page", 1, v),
+		PageSize:     app.getSingleIntegerParameter(queryParameters, "page_size", 10, v),
+		Sort:         app.getSingleQueryParameter(queryParameters, "sort", "created_at"),
+		SortSafeList: quoteSortSafeList,
+		Cursor:       app.getSingleQueryParameter(queryParameters, "cursor", ""),
+	}
+
+	data.ValidateFilters(v, filters)
+	if !v.IsEmpty() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	quotes, metadata, err := app.quoteModel.GetAll(criteria, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	data := envelope{
+		"quotes":    quotes,
+		"@metadata": metadata,
+	}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Make a quote public
+func (app *application) approveQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	app.moderateQuote(w, r, data.StatusApproved)
+}
+
+// Keep a quote hidden. The reason is shown to the submitter
+func (app *application) rejectQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	app.moderateQuote(w, r, data.StatusRejected)
+}
+
+// Save a moderator's decision on a quote. An approved quote can still be
+// rejected later and the other way round
+func (app *application) moderateQuote(w http.ResponseWriter, r *http.Request, status string) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var incomingData struct {
+		Reason string `json:"reason"`
+	}
+
+	err = app.readJSON(w, r, &incomingData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	quote, err := app.quoteModel.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// make sure the moderator is judging the version they think they are
+	if !app.checkQuoteVersion(w, r, quote) {
+		return
+	}
+
+	quote.Status = status
+	quote.ModerationReason = incomingData.Reason
+
+	v := validator.New()
+	data.ValidateModeration(v, quote)
+	if !v.IsEmpty() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	err = app.quoteModel.Moderate(quote, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateQuote):
+			app.duplicateQuoteResponse(w, r, quote)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", quoteETag(quote, formatJSON))
+
+	data := envelope{
+		"quote": quote,
+	}
+	err = app.writeJSON(w, http.StatusOK, data, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
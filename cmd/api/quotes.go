@@ -5,8 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 
 	"github.com/aiycoleman/qod/internal/data"
+	"github.com/aiycoleman/qod/internal/policy"
 	"github.com/aiycoleman/qod/internal/validator"
 )
 
@@ -15,8 +19,10 @@ func (app *application) createQuoteHandler(w http.ResponseWriter,
 	// create a struct to hold a quote
 	// struct tags[“] to make the names display in lowercase
 	var incomingData struct {
-		Content string `json:"content"`
-		Author  string `json:"author"`
+		Content  string   `json:"content"`
+		Author   string   `json:"author"`
+		AuthorID int64    `json:"author_id"`
+		Tags     []string `json:"tags"`
 	}
 	// perform the decoding
 	err := app.readJSON(w, r, &incomingData)
@@ -25,36 +31,82 @@ func (app *application) createQuoteHandler(w http.ResponseWriter,
 		return
 	}
 
+	// the logged in user is the one submitting the quote
+	user := app.contextGetUser(r)
+
 	quote := &data.Quote{
-		Content: incomingData.Content,
-		Author:  incomingData.Author,
+		Content:     validator.CleanText(incomingData.Content),
+		Author:      validator.CleanText(incomingData.Author),
+		Tags:        data.NormalizeTags(incomingData.Tags),
+		SubmittedBy: &data.Submitter{ID: user.ID, Username: user.Username},
 	}
 
 	// Initialize a Validator instance
 	v := validator.New()
 
+	// an author_id picks the author, otherwise we go by the author string
+	if incomingData.AuthorID != 0 {
+		err = app.setQuoteAuthorByID(v, quote, incomingData.AuthorID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
 	// Do the validation
-	data.ValidateQuote(v, quote)
+	data.ValidateQuote(v, quote, app.config.limits)
 	if !v.IsEmpty() {
-		app.failedValidationResponse(w, r, v.Errors) // implemented later
+		app.failedValidationResponse(w, r, v) // implemented later
+		return
+	}
+
+	// some rules reject the quote, others send it to a moderator
+	violations := app.checkContentPolicy(quote)
+	if violations.Rejected() {
+		app.contentPolicyResponse(w, r, violations)
 		return
 	}
 
+	// the quote isn't shown publicly until a moderator approves it
+	moderator, err := app.isModerator(r)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	setSubmissionStatus(quote, moderator, violations)
+
 	// Add the quote to the database table
 	err = app.quoteModel.Insert(quote)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrDuplicateQuote):
+			app.duplicateQuoteResponse(w, r, quote)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
+	// quotes that read almost the same are allowed but worth a second look.
+	// The quote is saved by now, so if the lookup fails we still say so
+	// (without warnings) rather than have the client retry a saved quote
+	similar, err := app.quoteModel.GetSimilar(quote, app.config.search.duplicateThreshold, 5)
+	if err != nil {
+		app.logError(r, err)
+	}
+
 	// Set a location header (the path to the newly created quote)
 	headers := make(http.Header)
 	headers.Set("Location", fmt.Sprintf("/v1/quotes/%d", quote.ID))
+	headers.Set("ETag", quoteETag(quote, formatJSON))
 
 	// Send a JSON response with 201 (new resource createed) status code
 	data := envelope{
 		"quote": quote,
 	}
+	if len(similar) > 0 {
+		data["warnings"] = envelope{"similar": similar}
+	}
 
 	err = app.writeJSON(w, http.StatusCreated, data, headers)
 	if err != nil {
@@ -72,6 +124,21 @@ func (app *application) displayQuoteHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	// ?fields= and ?include= trim or enrich the quote
+	v := validator.New()
+	fieldset := app.readFieldset(r.URL.Query(), data.QuoteFieldsSafeList, data.QuoteIncludeSafeList, v)
+
+	// the Accept header (or ?format=) picks the response format
+	format, ok := app.negotiateFormat(r, v)
+	if !ok {
+		app.notAcceptableResponse(w, r)
+		return
+	}
+	if !v.IsEmpty() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
 	// Call Get(to retrieve data based on id)
 	quote, err := app.quoteModel.Get(id)
 	if err != nil {
@@ -84,11 +151,46 @@ func (app *application) displayQuoteHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	// quotes waiting for (or failing) moderation are only shown to the
+	// submitter and moderators
+	visible, err := app.canViewQuote(r, quote)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !visible {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	// the client already has this version
+	etag := quoteETag(quote, format)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		// caches must keep each format separately, see writeResponse
+		w.Header().Add("Vary", "Accept")
+		w.Header().Set("ETag", etag)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	// every display counts towards the popularity sort. The count is saved
+	// in the background so it shows up in views a little later
+	app.views.add(quote.ID)
+
+	headers := make(http.Header)
+	headers.Set("ETag", etag)
+
+	shaped, err := app.shapeQuote(quote, fieldset)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
 	// display quote
 	data := envelope{
-		"quote": quote,
+		"quote": shaped,
 	}
-	err = app.writeJSON(w, http.StatusOK, data, nil)
+	err = app.writeResponse(w, http.StatusOK, format, data, "quote", quoteColumns(fieldset), headers)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -116,10 +218,28 @@ func (app *application) updateQuoteHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// only the owner or a moderator may edit the quote
+	allowed, err := app.canModifyQuote(r, quote)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !allowed {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	// make sure the client is editing the version they think they are
+	if !app.checkQuoteVersion(w, r, quote) {
+		return
+	}
+
 	// temporary incoming data struct
 	var incomingData struct {
-		Content *string `json:"content"`
-		Author  *string `json:"author"`
+		Content  *string  `json:"content"`
+		Author   *string  `json:"author"`
+		AuthorID *int64   `json:"author_id"`
+		Tags     []string `json:"tags"`
 	}
 
 	// perform decoding
@@ -129,34 +249,82 @@ func (app *application) updateQuoteHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// quotes saved before text was tidied are tidied now, otherwise the
+	// checks below would fail on fields the client didn't send
+	quote.Content = validator.CleanText(quote.Content)
+	quote.Author = validator.CleanText(quote.Author)
+
 	// Check to see which fields need to be updated
 	// if  nill;, no update needed for any feild
 	if incomingData.Content != nil {
-		quote.Content = *incomingData.Content
+		quote.Content = validator.CleanText(*incomingData.Content)
 	}
 
+	// a new author string has to be matched to an author again
 	if incomingData.Author != nil {
-		quote.Author = *incomingData.Author
+		quote.Author = validator.CleanText(*incomingData.Author)
+		quote.AuthorID = 0
+	}
+
+	// a missing tags field leaves them alone, [] removes them all
+	if incomingData.Tags != nil {
+		quote.Tags = data.NormalizeTags(incomingData.Tags)
 	}
 
-	// Validate
 	v := validator.New()
-	data.ValidateQuote(v, quote)
+
+	// an author_id wins over an author string
+	if incomingData.AuthorID != nil {
+		err = app.setQuoteAuthorByID(v, quote, *incomingData.AuthorID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	// Validate
+	data.ValidateQuote(v, quote, app.config.limits)
 	if !v.IsEmpty() {
-		app.failedValidationResponse(w, r, v.Errors)
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
-	// Add the quote to the database table
-	err = app.quoteModel.Update(quote)
+	// some rules reject the quote, others send it to a moderator
+	violations := app.checkContentPolicy(quote)
+	if violations.Rejected() {
+		app.contentPolicyResponse(w, r, violations)
+		return
+	}
+
+	// an edited quote has to be approved again
+	moderator, err := app.isModerator(r)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
+	setSubmissionStatus(quote, moderator, violations)
+
+	// Add the quote to the database table
+	err = app.quoteModel.Update(quote, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateQuote):
+			app.duplicateQuoteResponse(w, r, quote)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", quoteETag(quote, formatJSON))
+
 	data := envelope{
 		"quote": quote,
 	}
-	err = app.writeJSON(w, http.StatusOK, data, nil)
+	err = app.writeJSON(w, http.StatusOK, data, headers)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -172,12 +340,41 @@ func (app *application) deleteQuoteHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err = app.quoteModel.Delete(id)
+	quote, err := app.quoteModel.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// only the owner or a moderator may delete the quote
+	allowed, err := app.canModifyQuote(r, quote)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !allowed {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	// make sure the client is deleting the version they think they are
+	if !app.checkQuoteVersion(w, r, quote) {
+		return
+	}
+
+	err = app.quoteModel.Delete(id, quote.Version)
 
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
 			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
@@ -185,7 +382,7 @@ func (app *application) deleteQuoteHandler(w http.ResponseWriter, r *http.Reques
 	}
 
 	// display the quote
-	data := envelope{"message": "quote successfully deleted"}
+	data := envelope{"message": "quote moved to the trash"}
 	err = app.writeJSON(w, http.StatusOK, data, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -194,48 +391,316 @@ func (app *application) deleteQuoteHandler(w http.ResponseWriter, r *http.Reques
 }
 
 func (app *application) listQuotesHandler(w http.ResponseWriter, r *http.Request) {
+	app.listQuotes(w, r, 0)
+}
+
+// List the quotes submitted by one user
+func (app *application) listUserQuotesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	// make sure the user exists so we don't send back an empty list for nobody
+	_, err = app.userModel.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.listQuotes(w, r, id)
+}
+
+// The fields quotes can be sorted by. Several can be combined, e.g.
+// ?sort=-created_at,author
+var quoteSortSafeList = []string{
+	"id", "author", "created_at", "updated_at", "length", "popularity",
+	"-id", "-author", "-created_at", "-updated_at", "-length", "-popularity",
+}
+
+// Read the query parameters that pick which quotes are listed. A userID of
+// 0 means quotes from every user
+func (app *application) readQuoteCriteria(queryParameters url.Values, userID int64, v *validator.Validator) data.QuoteCriteria {
+	var criteria data.QuoteCriteria
+
+	criteria.Content = app.getSingleQueryParameter(queryParameters, "content", "")
+
+	criteria.Author = app.getSingleQueryParameter(queryParameters, "author", "")
+
+	// ?tags=a,b only returns quotes tagged with both a and b
+	criteria.Tags = data.NormalizeTags(app.getMultipleQueryParameters(queryParameters, "tags", []string{}))
+
+	criteria.UserID = userID
+
+	// only approved quotes are listed publicly
+	criteria.Status = data.StatusApproved
+
+	// ?match=fuzzy tolerates typos in the content and author filters
+	match := app.getSingleQueryParameter(queryParameters, "match", "exact")
+	v.Check(validator.PermittedValue(match, "exact", "fuzzy"), "match", validator.NewError("not_permitted", "values", "exact, fuzzy"))
+	criteria.Fuzzy = match == "fuzzy"
+	criteria.Threshold = app.config.search.fuzzyThreshold
+
+	// ?created_after=2024-01-01&created_before=2024-02-01 covers January
+	criteria.CreatedAfter = app.getSingleTimeParameter(queryParameters, "created_after", v)
+	criteria.CreatedBefore = app.getSingleTimeParameter(queryParameters, "created_before", v)
+	if criteria.CreatedAfter != nil && criteria.CreatedBefore != nil {
+		v.Check(criteria.CreatedAfter.Before(*criteria.CreatedBefore), "created_before", validator.NewError("later_than", "field", "created_after"))
+	}
+
+	return criteria
+}
+
+// Send back a page of quotes using the query parameters. A userID of 0
+// lists quotes from every user
+func (app *application) listQuotes(w http.ResponseWriter, r *http.Request, userID int64) {
 	var queryParametersData struct {
-		Content string
-		Author  string
+		data.QuoteCriteria
 		data.Filters
 	}
 	// get the query parameters from the URL
 	queryParameters := r.URL.Query()
 
-	// Load the query parameters into our struct
-	queryParametersData.Content = app.getSingleQueryParameter(queryParameters, "content", "")
-
-	queryParametersData.Author = app.getSingleQueryParameter(queryParameters, "author", "")
-
 	// Validation instance
 	v := validator.New()
 
+	// Load the query parameters into our struct
+	queryParametersData.QuoteCriteria = app.readQuoteCriteria(queryParameters, userID, v)
+
 	queryParametersData.Filters.Page = app.getSingleIntegerParameter(queryParameters, "page", 1, v)
 	queryParametersData.Filters.PageSize = app.getSingleIntegerParameter(queryParameters, "page_size", 10, v)
 	queryParametersData.Filters.Sort = app.getSingleQueryParameter(queryParameters, "sort", "id")
-	queryParametersData.Filters.SortSafeList = []string{"id", "author", "-id", "-author"}
+	queryParametersData.Filters.SortSafeList = quoteSortSafeList
+	// the next_cursor from a previous page, an alternative to ?page=
+	queryParametersData.Filters.Cursor = app.getSingleQueryParameter(queryParameters, "cursor", "")
+
+	// ?fields= and ?include= trim or enrich each quote
+	fieldset := app.readFieldset(queryParameters, data.QuoteFieldsSafeList, data.QuoteIncludeSafeList, v)
+
+	// the Accept header (or ?format=) picks the response format
+	format, ok := app.negotiateFormat(r, v)
+	if !ok {
+		app.notAcceptableResponse(w, r)
+		return
+	}
 
 	// Check if the filters are valid
 	data.ValidateFilters(v, queryParametersData.Filters)
 	if !v.IsEmpty() {
-		app.failedValidationResponse(w, r, v.Errors)
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	quotes, metadata, err := app.quoteModel.GetAll(queryParametersData.QuoteCriteria, queryParametersData.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	quotes, metadata, err := app.quoteModel.GetAll(queryParametersData.Content,
-		queryParametersData.Author,
-		queryParametersData.Filters)
+	shaped, err := app.shapeQuotes(quotes, fieldset)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
 	data := envelope{
-		"quotes":    quotes,
+		"quotes":    shaped,
 		"@metadata": metadata,
 	}
+	err = app.writeResponse(w, http.StatusOK, format, data, "quotes", quoteColumns(fieldset), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Display one or more random quotes, optionally matching the content and
+// author filters
+func (app *application) randomQuotesHandler(w http.ResponseWriter, r *http.Request) {
+	queryParameters := r.URL.Query()
+
+	content := app.getSingleQueryParameter(queryParameters, "content", "")
+	author := app.getSingleQueryParameter(queryParameters, "author", "")
+
+	v := validator.New()
+
+	count := app.getSingleIntegerParameter(queryParameters, "count", 1, v)
+	v.Check(count > 0, "count", validator.NewError("positive"))
+	v.Check(count <= 10, "count", validator.NewError("max_value", "max", 10))
+	if !v.IsEmpty() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	quotes, err := app.quoteModel.GetRandom(content, author, count)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// nothing matched the filters
+	if len(quotes) == 0 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	data := envelope{
+		"quotes": quotes,
+	}
 	err = app.writeJSON(w, http.StatusOK, data, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+// Check if the user making the request may change the quote. Owners can
+// change their own quotes, moderators can change anybody's
+func (app *application) canModifyQuote(r *http.Request, quote *data.Quote) (bool, error) {
+	user := app.contextGetUser(r)
+
+	if quote.IsOwnedBy(user.ID) {
+		return true, nil
+	}
+
+	return app.isModerator(r)
+}
+
+// Check if the user making the request may see the quote. Approved quotes
+// are public, the rest are only seen by their owner and moderators
+func (app *application) canViewQuote(r *http.Request, quote *data.Quote) (bool, error) {
+	if quote.Status == data.StatusApproved {
+		return true, nil
+	}
+
+	if app.contextGetUser(r).IsAnonymous() {
+		return false, nil
+	}
+
+	return app.canModifyQuote(r, quote)
+}
+
+// The same as canViewQuote for a quote we only have the id of. A quote that
+// has gone in the meantime can't be seen
+func (app *application) canViewQuoteID(r *http.Request, id int64) (bool, error) {
+	quote, err := app.quoteModel.Get(id)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return app.canViewQuote(r, quote)
+}
+
+// Check if the user making the request has the quotes:moderate permission
+func (app *application) isModerator(r *http.Request) (bool, error) {
+	permissions, err := app.permissionModel.GetAllForUser(app.contextGetUser(r).ID)
+	if err != nil {
+		return false, err
+	}
+
+	return permissions.Include("quotes:moderate"), nil
+}
+
+// Set the status of a quote a user has added or changed. Everybody's
+// quotes wait for a moderator, except for moderators whose new quotes are
+// approved straight away and whose edits leave the status alone. Quotes
+// the content policy flagged say why in the moderation reason
+func setSubmissionStatus(quote *data.Quote, moderator bool, violations policy.Violations) {
+	if moderator {
+		if quote.Status == "" {
+			quote.Status = data.StatusApproved
+		}
+		return
+	}
+
+	quote.Status = data.StatusPending
+	quote.ModerationReason = ""
+	if violations.Moderated() {
+		quote.ModerationReason = "flagged by the content policy: " + violations.Summary()
+	}
+}
+
+// Run the content policy over the text of a quote
+func (app *application) checkContentPolicy(quote *data.Quote) policy.Violations {
+	violations := app.contentPolicy.Check("content", quote.Content)
+	return append(violations, app.contentPolicy.Check("author", quote.Author)...)
+}
+
+// The ETag of a quote changes every time the quote is updated. Each
+// format is a different body so it gets its own ETag, JSON keeps the plain
+// version number it always had
+func quoteETag(quote *data.Quote, format string) string {
+	if format == formatJSON {
+		return fmt.Sprintf(`"%d"`, quote.Version)
+	}
+	return fmt.Sprintf(`"%d-%s"`, quote.Version, format)
+}
+
+// Check a comma-separated If-Match/If-None-Match header against an ETag
+func etagMatches(header string, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// Compare the version the client expects, sent in If-Match or
+// X-Expected-Version, with the quote's current version. If they differ the
+// error response is sent and false is returned
+func (app *application) checkQuoteVersion(w http.ResponseWriter, r *http.Request, quote *data.Quote) bool {
+	// an ETag from any format will do, they all name the same version
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch != "" {
+		matched := false
+		for _, f := range responseFormats {
+			matched = matched || etagMatches(ifMatch, quoteETag(quote, f.name))
+		}
+		if !matched {
+			app.preconditionFailedResponse(w, r)
+			return false
+		}
+	}
+
+	expectedVersion := r.Header.Get("X-Expected-Version")
+	if expectedVersion != "" {
+		version, err := strconv.ParseInt(expectedVersion, 10, 32)
+		if err != nil {
+			app.badRequestResponse(w, r, errors.New("the X-Expected-Version header must be an integer"))
+			return false
+		}
+		if int32(version) != quote.Version {
+			app.editConflictResponse(w, r)
+			return false
+		}
+	}
+
+	return true
+}
+
+// Point the quote at the author the client picked by id. An unknown id is
+// reported as a validation error
+func (app *application) setQuoteAuthorByID(v *validator.Validator, quote *data.Quote, authorID int64) error {
+	author, err := app.authorModel.Get(authorID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("author_id", validator.NewError("unknown_author"))
+			return nil
+		default:
+			return err
+		}
+	}
+
+	quote.AuthorID = author.ID
+	quote.Author = author.Name
+	return nil
+}
@@ -0,0 +1,297 @@
+// Filename: cmd/api/revisions.go
+package main
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/aiycoleman/qod/internal/data"
+	"github.com/aiycoleman/qod/internal/validator"
+)
+
+// List the saved versions of a quote, newest first
+func (app *application) listQuoteRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	queryParameters := r.URL.Query()
+
+	v := validator.New()
+
+	filters := data.Filters{
+		Page:         app.getSingleIntegerParameter(queryParameters, "page", 1, v),
+		PageSize:     app.getSingleIntegerParameter(queryParameters, "page_size", 10, v),
+		Sort:         "-version",
+		SortSafeList: []string{"-version"},
+	}
+
+	data.ValidateFilters(v, filters)
+	if !v.IsEmpty() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	// the history of a deleted quote goes with it into the trash
+	quote, err := app.quoteModel.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// quotes waiting for (or failing) moderation are only shown to the
+	// submitter and moderators
+	visible, err := app.canViewQuote(r, quote)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !visible {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	revisions, metadata, err := app.revisionModel.GetAllForQuote(id, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	data := envelope{
+		"revisions": revisions,
+		"@metadata": metadata,
+	}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Show one version of a quote and what changed since the version before it,
+// or since the version given by ?compare=
+func (app *application) showQuoteRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	version, err := app.readVersionParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+	compare := app.getSingleIntegerParameter(r.URL.Query(), "compare", 0, v)
+	v.Check(compare >= 0, "compare", validator.NewError("not_negative"))
+	if !v.IsEmpty() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	quote, err := app.quoteModel.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// quotes waiting for (or failing) moderation are only shown to the
+	// submitter and moderators
+	visible, err := app.canViewQuote(r, quote)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !visible {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	revision, err := app.revisionModel.Get(id, version)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var other *data.QuoteRevision
+	if compare > 0 {
+		other, err = app.revisionModel.Get(id, int32(compare))
+	} else {
+		other, err = app.revisionModel.GetPrevious(id, version)
+	}
+	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if compare > 0 && other == nil {
+		v.AddError("compare", validator.NewError("unknown_version"))
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	// the first version has nothing to be compared with
+	var diff *data.RevisionDiff
+	if other != nil {
+		d := data.DiffRevisions(other, revision)
+		diff = &d
+	}
+
+	data := envelope{
+		"revision": revision,
+		"diff":     diff,
+	}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Bring back an older version of a quote. The old content is saved as a
+// new version so the history is never rewritten
+func (app *application) revertQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Version int32 `json:"version"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	quote, err := app.quoteModel.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// only the owner or a moderator may revert the quote
+	allowed, err := app.canModifyQuote(r, quote)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !allowed {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	// make sure the client is reverting the version they think they are
+	if !app.checkQuoteVersion(w, r, quote) {
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.Version > 0, "version", validator.NewError("required"))
+	v.Check(input.Version < quote.Version, "version", validator.NewError("not_earlier_version"))
+	if !v.IsEmpty() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	revision, err := app.revisionModel.Get(id, input.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("version", validator.NewError("unknown_version"))
+			app.failedValidationResponse(w, r, v)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// older versions may have been saved before text was tidied
+	quote.Content = validator.CleanText(revision.Content)
+	quote.Author = validator.CleanText(revision.Author)
+	quote.AuthorID = revision.AuthorID
+	quote.Tags = slices.Clone(revision.Tags)
+
+	// the author may have been deleted since, then the name is matched again
+	_, err = app.authorModel.Get(quote.AuthorID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			quote.AuthorID = 0
+		default:
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	// the rules may have changed since the old version was saved
+	data.ValidateQuote(v, quote, app.config.limits)
+	if !v.IsEmpty() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	// some rules reject the quote, others send it to a moderator
+	violations := app.checkContentPolicy(quote)
+	if violations.Rejected() {
+		app.contentPolicyResponse(w, r, violations)
+		return
+	}
+
+	// a reverted quote has to be approved again
+	moderator, err := app.isModerator(r)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	setSubmissionStatus(quote, moderator, violations)
+
+	err = app.quoteModel.Update(quote, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateQuote):
+			app.duplicateQuoteResponse(w, r, quote)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", quoteETag(quote, formatJSON))
+
+	data := envelope{
+		"quote": quote,
+	}
+	err = app.writeJSON(w, http.StatusOK, data, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -20,12 +20,46 @@ func (app *application) routes() http.Handler {
 
 	// setup routes
 	router.HandlerFunc(http.MethodGet, "/v1/healthcheck", app.healthcheckHandler)
-	router.HandlerFunc(http.MethodPost, "/v1/quotes", app.createQuoteHandler)
-	router.HandlerFunc(http.MethodGet, "/v1/quotes/:id", app.displayQuoteHandler)
-	router.HandlerFunc(http.MethodPatch, "/v1/quotes/:id", app.updateQuoteHandler)
-	router.HandlerFunc(http.MethodDelete, "/v1/quotes/:id", app.deleteQuoteHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/quotes", app.requirePermission("quotes:write", app.createQuoteHandler))
+	// httprouter won't have /v1/quotes/import next to /v1/quotes/:id/restore.
+	// A POST to any other quote is answered like httprouter's own 405s
+	router.HandlerFunc(http.MethodPost, "/v1/quotes/:id", app.withFixedSegments("id", map[string]http.HandlerFunc{
+		"import": app.requirePermission("quotes:write", app.importQuotesHandler),
+	}, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", "GET, PATCH, DELETE, OPTIONS")
+		app.methodNotAllowedResponse(w, r)
+	}))
+	router.HandlerFunc(http.MethodPost, "/v1/quotes/:id/restore", app.requirePermission("quotes:write", app.restoreQuoteHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/quotes/:id/revert", app.requirePermission("quotes:write", app.revertQuoteHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/quotes/:id/revisions", app.listQuoteRevisionsHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/quotes/:id/revisions/:version", app.showQuoteRevisionHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/quotes/:id", app.withFixedSegments("id", map[string]http.HandlerFunc{
+		"today":  app.showDailyQuoteHandler,
+		"random": app.randomQuotesHandler,
+		"export": app.exportQuotesHandler,
+		"trash":  app.requirePermission("quotes:write", app.listTrashHandler),
+	}, app.displayQuoteHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/quotes/:id", app.requirePermission("quotes:write", app.updateQuoteHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/quotes/:id", app.requirePermission("quotes:write", app.deleteQuoteHandler))
 	router.HandlerFunc(http.MethodGet, "/v1/quotes", app.listQuotesHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/moderation/quotes", app.requirePermission("quotes:moderate", app.listModerationQueueHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/moderation/quotes/:id/approve", app.requirePermission("quotes:moderate", app.approveQuoteHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/moderation/quotes/:id/reject", app.requirePermission("quotes:moderate", app.rejectQuoteHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/tags", app.listTagsHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/search", app.searchQuotesHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/authors", app.listAuthorsHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/authors", app.requirePermission("authors:write", app.createAuthorHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/authors/:id", app.withFixedSegments("id", map[string]http.HandlerFunc{
+		"suggest": app.suggestAuthorsHandler,
+	}, app.displayAuthorHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/authors/:id", app.requirePermission("authors:write", app.updateAuthorHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/authors/:id", app.requirePermission("authors:write", app.deleteAuthorHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/daily-quotes/:date", app.requirePermission("daily_quotes:write", app.pinDailyQuoteHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/daily-quotes/:date", app.requirePermission("daily_quotes:write", app.unpinDailyQuoteHandler))
 	router.HandlerFunc(http.MethodPost, "/v1/users", app.registerUserHandler)
+	router.HandlerFunc(http.MethodPut, "/v1/users/activated", app.activateUserHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/users/:id/quotes", app.listUserQuotesHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/tokens/authentication", app.createAuthenticationTokenHandler)
 
-	return app.recoverPanic(app.enableCORS(app.rateLimit(router)))
+	return app.recoverPanic(app.enableCORS(app.rateLimit(app.authenticate(router))))
 }
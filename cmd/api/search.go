@@ -0,0 +1,51 @@
+// Filename: cmd/api/search.go
+package main
+
+import (
+	"net/http"
+
+	"github.com/aiycoleman/qod/internal/data"
+	"github.com/aiycoleman/qod/internal/validator"
+)
+
+// Search quote content and authors, best matches first
+func (app *application) searchQuotesHandler(w http.ResponseWriter, r *http.Request) {
+	var queryParametersData struct {
+		Search string
+		data.Filters
+	}
+
+	queryParameters := r.URL.Query()
+
+	queryParametersData.Search = app.getSingleQueryParameter(queryParameters, "q", "")
+
+	v := validator.New()
+
+	// results are always ordered by rank so there is only one sort
+	queryParametersData.Filters.Page = app.getSingleIntegerParameter(queryParameters, "page", 1, v)
+	queryParametersData.Filters.PageSize = app.getSingleIntegerParameter(queryParameters, "page_size", 10, v)
+	queryParametersData.Filters.Sort = "rank"
+	queryParametersData.Filters.SortSafeList = []string{"rank"}
+
+	data.ValidateSearch(v, queryParametersData.Search)
+	data.ValidateFilters(v, queryParametersData.Filters)
+	if !v.IsEmpty() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	results, metadata, err := app.quoteModel.Search(queryParametersData.Search, queryParametersData.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	data := envelope{
+		"results":   results,
+		"@metadata": metadata,
+	}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
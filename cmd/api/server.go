@@ -25,6 +25,14 @@ func (app *application) serve() error {
 		ErrorLog:     slog.NewLogLogger(app.logger.Handler(), slog.LevelError),
 	}
 
+	// the scheduled jobs run until the server shuts down, which waits for
+	// them to finish what they are doing
+	jobs, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	app.background(func() { app.scheduleDailyQuotes(jobs) })
+	app.background(func() { app.scheduleTrashPurge(jobs) })
+	app.background(func() { app.saveViews(jobs) })
+
 	// create a channel to keep track of any errors during the shutdown process
 	shutdownError := make(chan error)
 	// create a goroutine that runs in the background listening
@@ -40,7 +48,14 @@ func (app *application) serve() error {
 		defer cancel()
 
 		// initiate the shutdown. If all okay this returns nil
-		shutdownError <- srv.Shutdown(ctx)
+		err := srv.Shutdown(ctx)
+		stopJobs()
+
+		// wait for background tasks (e.g. sending emails) to finish, even
+		// if the shutdown failed, then report how the shutdown went
+		app.logger.Info("completing background tasks", "address", srv.Addr)
+		app.wg.Wait()
+		shutdownError <- err
 	}()
 
 	app.logger.Info("starting server", "address", srv.Addr,
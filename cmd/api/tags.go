@@ -0,0 +1,23 @@
+// Filename: cmd/api/tags.go
+package main
+
+import (
+	"net/http"
+)
+
+// List every tag with the number of quotes using it
+func (app *application) listTagsHandler(w http.ResponseWriter, r *http.Request) {
+	tags, err := app.tagModel.GetAll()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	data := envelope{
+		"tags": tags,
+	}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
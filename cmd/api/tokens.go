@@ -0,0 +1,73 @@
+// Filename: cmd/api/tokens.go
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aiycoleman/qod/internal/data"
+	"github.com/aiycoleman/qod/internal/validator"
+)
+
+// Exchange an email and password for an authentication token
+func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var incomingData struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+
+	err := app.readJSON(w, r, &incomingData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	data.ValidateEmail(v, incomingData.Email)
+	data.ValidatePasswordPlaintext(v, incomingData.Password)
+	if !v.IsEmpty() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	// Is there a user with that email?
+	user, err := app.userModel.GetByEmail(incomingData.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.invalidCredentialsResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Does the password match?
+	match, err := user.Password.Matches(incomingData.Password)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !match {
+		app.invalidCredentialsResponse(w, r)
+		return
+	}
+
+	// the token is good for a day
+	token, err := app.tokenModel.New(user.ID, 24*time.Hour, data.ScopeAuthentication)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	data := envelope{
+		"authentication_token": token,
+	}
+	err = app.writeJSON(w, http.StatusCreated, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
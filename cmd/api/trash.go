@@ -0,0 +1,141 @@
+// Filename: cmd/api/trash.go
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aiycoleman/qod/internal/data"
+	"github.com/aiycoleman/qod/internal/validator"
+)
+
+// List the deleted quotes that can still be restored. Moderators see the
+// whole trash, everybody else only the quotes they submitted
+func (app *application) listTrashHandler(w http.ResponseWriter, r *http.Request) {
+	queryParameters := r.URL.Query()
+
+	v := validator.New()
+
+	filters := data.Filters{
+		Page:         app.getSingleIntegerParameter(queryParameters, "page", 1, v),
+		PageSize:     app.getSingleIntegerParameter(queryParameters, "page_size", 10, v),
+		Sort:         "id",
+		SortSafeList: []string{"id"},
+	}
+
+	data.ValidateFilters(v, filters)
+	if !v.IsEmpty() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	permissions, err := app.permissionModel.GetAllForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	userID := user.ID
+	if permissions.Include("quotes:moderate") {
+		userID = 0
+	}
+
+	quotes, metadata, err := app.quoteModel.GetTrash(userID, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	data := envelope{
+		"quotes":    quotes,
+		"@metadata": metadata,
+	}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Take a quote out of the trash
+func (app *application) restoreQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	quote, err := app.quoteModel.GetDeleted(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// only the owner or a moderator may restore the quote
+	allowed, err := app.canModifyQuote(r, quote)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !allowed {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	// make sure the client is restoring the version they think they are
+	if !app.checkQuoteVersion(w, r, quote) {
+		return
+	}
+
+	err = app.quoteModel.Restore(quote)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateQuote):
+			app.duplicateQuoteResponse(w, r, quote)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", quoteETag(quote, formatJSON))
+
+	data := envelope{
+		"quote": quote,
+	}
+	err = app.writeJSON(w, http.StatusOK, data, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Empty the trash of quotes deleted longer ago than the retention period,
+// checking once an hour until ctx is cancelled. A purge that has started
+// is left to finish
+func (app *application) scheduleTrashPurge(ctx context.Context) {
+	for {
+		purged, err := app.quoteModel.Purge(app.config.trash.retention)
+		if err != nil {
+			app.logger.Error(err.Error())
+		} else if purged > 0 {
+			app.logger.Info("purged quotes from the trash", "count", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Hour):
+		}
+	}
+}
@@ -4,6 +4,7 @@ package main
 import (
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/aiycoleman/qod/internal/data"
 	"github.com/aiycoleman/qod/internal/validator"
@@ -26,7 +27,7 @@ func (app *application) registerUserHandler(w http.ResponseWriter,
 
 	// we will add the password later after we have hashed it
 	user := &data.User{
-		Username:  incomingData.Username,
+		Username:  validator.CleanText(incomingData.Username),
 		Email:     incomingData.Email,
 		Activated: false,
 	}
@@ -41,9 +42,9 @@ func (app *application) registerUserHandler(w http.ResponseWriter,
 	// Perform validation for the User
 	v := validator.New()
 
-	data.ValidateUser(v, *user)
+	data.ValidateUser(v, *user, app.config.limits)
 	if !v.IsEmpty() {
-		app.failedValidationResponse(w, r, v.Errors)
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
@@ -51,14 +52,42 @@ func (app *application) registerUserHandler(w http.ResponseWriter,
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrDuplicateEmail):
-			v.AddError("email", "a user with this email address already exists")
-			app.failedValidationResponse(w, r, v.Errors)
+			v.AddError("email", validator.NewError("duplicate_email"))
+			app.failedValidationResponse(w, r, v)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
 		return
 	}
 
+	// new users can read quotes, anything more is granted later
+	err = app.permissionModel.AddForUser(user.ID, "quotes:read")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// the activation token is good for three days
+	token, err := app.tokenModel.New(user.ID, 3*24*time.Hour, data.ScopeActivation)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// send the welcome email in the background so the client isn't kept waiting
+	app.background(func() {
+		emailData := map[string]any{
+			"activationToken": token.Plaintext,
+			"userID":          user.ID,
+			"username":        user.Username,
+		}
+
+		err := app.mailer.Send(user.Email, "user_welcome.tmpl", emailData)
+		if err != nil {
+			app.logger.Error(err.Error())
+		}
+	})
+
 	data := envelope{
 		"user": user,
 	}
@@ -69,3 +98,66 @@ func (app *application) registerUserHandler(w http.ResponseWriter,
 		return
 	}
 }
+
+// Activate a user account using the token they were sent at registration
+func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Request) {
+	var incomingData struct {
+		TokenPlaintext string `json:"token"`
+	}
+
+	err := app.readJSON(w, r, &incomingData)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	data.ValidateTokenPlaintext(v, incomingData.TokenPlaintext)
+	if !v.IsEmpty() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	// Who does the token belong to?
+	user, err := app.userModel.GetForToken(data.ScopeActivation, incomingData.TokenPlaintext)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("token", validator.NewError("invalid_token"))
+			app.failedValidationResponse(w, r, v)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user.Activated = true
+
+	// Update checks the version so two activations can't race each other
+	err = app.userModel.Update(user)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// the account is active, the activation tokens are no longer needed
+	err = app.tokenModel.DeleteAllForUser(data.ScopeActivation, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	data := envelope{
+		"user": user,
+	}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,62 @@
+// Filename: cmd/api/views.go
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Quote views counted since they were last saved. Saving them in batches
+// keeps every read from turning into a write on the quote's row
+type viewCounter struct {
+	mu     sync.Mutex
+	counts map[int64]int64
+}
+
+// Count one view of a quote
+func (c *viewCounter) add(id int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.counts == nil {
+		c.counts = make(map[int64]int64)
+	}
+	c.counts[id]++
+}
+
+// Hand over the counts so far and start again from zero
+func (c *viewCounter) take() map[int64]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counts := c.counts
+	c.counts = nil
+	return counts
+}
+
+// Save the counted views every few seconds until ctx is cancelled, then
+// save whatever is left
+func (app *application) saveViews(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			app.flushViews()
+			return
+		case <-time.After(10 * time.Second):
+			app.flushViews()
+		}
+	}
+}
+
+func (app *application) flushViews() {
+	counts := app.views.take()
+	if len(counts) == 0 {
+		return
+	}
+
+	err := app.quoteModel.AddViews(counts)
+	if err != nil {
+		app.logger.Error(err.Error(), "quotes", len(counts))
+	}
+}
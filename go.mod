@@ -8,5 +8,6 @@ require github.com/lib/pq v1.10.9
 
 require (
 	golang.org/x/crypto v0.42.0
+	golang.org/x/text v0.29.0
 	golang.org/x/time v0.13.0
 )
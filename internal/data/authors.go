@@ -0,0 +1,505 @@
+// Filename: internal/data/authors.go
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aiycoleman/qod/internal/validator"
+	"github.com/lib/pq"
+)
+
+// The author still has quotes so it can't be deleted
+var ErrAuthorHasQuotes = errors.New("author has quotes")
+
+// The author's only quotes are in the trash, which still keeps the author
+// in use until they are purged
+var ErrAuthorHasTrashedQuotes = errors.New("author has quotes in the trash")
+
+// An author that quotes are attributed to. Aliases are the other names the
+// same person is known by ("Einstein", "A. Einstein")
+type Author struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Aliases   []string  `json:"aliases"`
+	Bio       string    `json:"bio"`
+	BirthYear *int32    `json:"birth_year"`
+	DeathYear *int32    `json:"death_year"`
+	CreatedAt time.Time `json:"-"`
+	Version   int32     `json:"version"`
+}
+
+// Performs the validation checks
+func ValidateAuthor(v *validator.Validator, author *Author, limits TextLimits) {
+	// the name ends up in quotes.author so it has the same limit
+	validateText(v, "name", author.Name, limits.Author)
+
+	v.Check(len(author.Aliases) <= 10, "aliases", validator.NewError("max_items", "max", 10))
+	v.Check(validator.Unique(author.Aliases), "aliases", validator.NewError("duplicate_values"))
+	for _, alias := range author.Aliases {
+		v.Check(validator.NotBlank(alias), "aliases", validator.NewError("empty_values"))
+		v.Check(validator.MaxChars(alias, limits.Author), "aliases", validator.NewError("max_item_chars", "max", limits.Author))
+		v.Check(validator.IsNFC(alias) && validator.IsTrimmed(alias) && validator.NoControlCharacters(alias), "aliases", validator.NewError("untidy_values"))
+	}
+
+	// the bio is optional and may have line breaks and tabs
+	v.Check(validator.MaxChars(author.Bio, limits.Bio), "bio", validator.NewError("max_chars", "max", limits.Bio))
+	v.Check(validator.IsNFC(author.Bio), "bio", validator.NewError("not_nfc"))
+	v.Check(validator.NoControlCharacters(author.Bio, '\n', '\t'), "bio", validator.NewError("control_characters"))
+
+	currentYear := int32(time.Now().Year())
+	if author.BirthYear != nil {
+		v.Check(*author.BirthYear <= currentYear, "birth_year", validator.NewError("in_future"))
+	}
+	if author.DeathYear != nil {
+		v.Check(*author.DeathYear <= currentYear, "death_year", validator.NewError("in_future"))
+	}
+	if author.BirthYear != nil && author.DeathYear != nil {
+		v.Check(*author.DeathYear >= *author.BirthYear, "death_year", validator.NewError("before_birth"))
+	}
+}
+
+// The AuthorModel expects a connection pool
+type AuthorModel struct {
+	DB *sql.DB
+}
+
+// Insert a new row in the authors table
+func (a AuthorModel) Insert(author *Author) error {
+	query := `
+		INSERT INTO authors (name, aliases, bio, birth_year, death_year)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, version
+		`
+	// a nil slice would be stored as NULL instead of no aliases
+	if author.Aliases == nil {
+		author.Aliases = []string{}
+	}
+	args := []any{author.Name, pq.Array(author.Aliases), author.Bio, author.BirthYear, author.DeathYear}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := a.DB.QueryRowContext(ctx, query, args...).Scan(&author.ID, &author.CreatedAt, &author.Version)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err, "authors_name_idx"):
+			return ErrDuplicateAuthor
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// Get a specific author
+func (a AuthorModel) Get(id int64) (*Author, error) {
+	// check if the id is valid
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT id, name, aliases, bio, birth_year, death_year, created_at, version
+		FROM authors
+		WHERE id = $1
+		`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return a.scanOne(a.DB.QueryRowContext(ctx, query, id))
+}
+
+// Get the authors with the given ids, keyed by id. Ids without an author
+// are left out
+func (a AuthorModel) GetMany(ids []int64) (map[int64]*Author, error) {
+	query := `
+		SELECT id, name, aliases, bio, birth_year, death_year, created_at, version
+		FROM authors
+		WHERE id = ANY($1)
+		`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := a.DB.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	authors := make(map[int64]*Author, len(ids))
+
+	for rows.Next() {
+		var author Author
+		err := rows.Scan(
+			&author.ID,
+			&author.Name,
+			pq.Array(&author.Aliases),
+			&author.Bio,
+			&author.BirthYear,
+			&author.DeathYear,
+			&author.CreatedAt,
+			&author.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		authors[author.ID] = &author
+	}
+
+	// check for errors from iterating over rows
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return authors, nil
+}
+
+// Get the author whose name or one of whose aliases matches, ignoring case
+func (a AuthorModel) GetByName(name string) (*Author, error) {
+	query := `
+		SELECT id, name, aliases, bio, birth_year, death_year, created_at, version
+		FROM authors
+		WHERE lower(name) = lower($1)
+		OR EXISTS (SELECT 1 FROM unnest(aliases) AS alias WHERE lower(alias) = lower($1))
+		ORDER BY lower(name) = lower($1) DESC, id ASC
+		LIMIT 1
+		`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return a.scanOne(a.DB.QueryRowContext(ctx, query, strings.TrimSpace(name)))
+}
+
+// Read a single author row
+func (a AuthorModel) scanOne(row *sql.Row) (*Author, error) {
+	var author Author
+
+	err := row.Scan(
+		&author.ID,
+		&author.Name,
+		pq.Array(&author.Aliases),
+		&author.Bio,
+		&author.BirthYear,
+		&author.DeathYear,
+		&author.CreatedAt,
+		&author.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &author, nil
+}
+
+// Update an author. The version number has to match or ErrEditConflict is
+// returned. A new name is copied onto the author's quotes in the same
+// transaction so the author string in quote responses stays correct, and
+// it counts as an edit of those quotes by the editor
+func (a AuthorModel) Update(author *Author, editorID int64) error {
+	query := `
+		UPDATE authors
+		SET name = $1, aliases = $2, bio = $3, birth_year = $4, death_year = $5, version = version + 1
+		WHERE id = $6 AND version = $7
+		RETURNING version
+		`
+	// a nil slice would be stored as NULL instead of no aliases
+	if author.Aliases == nil {
+		author.Aliases = []string{}
+	}
+	args := []any{
+		author.Name,
+		pq.Array(author.Aliases),
+		author.Bio,
+		author.BirthYear,
+		author.DeathYear,
+		author.ID,
+		author.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := a.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// does nothing once the transaction is committed
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&author.Version)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err, "authors_name_idx"):
+			return ErrDuplicateAuthor
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	err = renameQuoteAuthor(ctx, tx, author, editorID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Copy an author's new name onto their quotes. The fingerprint includes the
+// author so it is worked out again for each quote. A quote that now matches
+// another quote is left without a fingerprint, the same as the duplicates
+// that were already in the table when fingerprints were added. Each quote
+// gets a new version (so cached copies and ETags are out of date) and the
+// change goes into its revisions
+func renameQuoteAuthor(ctx context.Context, tx *sql.Tx, author *Author, editorID int64) error {
+	rows, err := tx.QueryContext(ctx, `SELECT id, content FROM quotes WHERE author_id = $1 AND author <> $2 FOR UPDATE`,
+		author.ID, author.Name)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	// read them all first, the connection can't run the updates while the
+	// rows are still open
+	fingerprints := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var content string
+		err = rows.Scan(&id, &content)
+		if err != nil {
+			return err
+		}
+		fingerprints[id] = Fingerprint(content, author.Name)
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE quotes
+		SET author = $1,
+		    fingerprint = CASE
+		        WHEN deleted_at IS NULL AND status <> 'rejected' AND EXISTS (
+		            SELECT 1 FROM quotes o WHERE o.fingerprint = $2 AND o.id <> quotes.id AND o.deleted_at IS NULL AND o.status <> 'rejected'
+		        ) THEN NULL
+		        ELSE $2
+		    END,
+		    updated_at = NOW(), version = version + 1
+		WHERE id = $3
+		`
+
+	for id, fingerprint := range fingerprints {
+		// make sure the version being replaced is in the history, as
+		// QuoteModel.Update does
+		err = writeRevision(ctx, tx, id, nil)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, query, author.Name, fingerprint, id)
+		if err != nil {
+			return err
+		}
+
+		err = writeRevision(ctx, tx, id, &editorID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete an author that has no quotes
+func (a AuthorModel) Delete(id int64) error {
+	// check if the id is valid
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+		DELETE FROM authors
+		WHERE id = $1
+		`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := a.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		// quotes.author_id stops us deleting an author that is in use,
+		// quotes in the trash included
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			var live bool
+			err = a.DB.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM quotes WHERE author_id = $1 AND deleted_at IS NULL)`, id).Scan(&live)
+			if err != nil {
+				return err
+			}
+			if !live {
+				return ErrAuthorHasTrashedQuotes
+			}
+			return ErrAuthorHasQuotes
+		}
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// The SQL for each column the authors list can be sorted by
+var authorSortExpressions = map[string]string{
+	"id":   "id",
+	"name": "name",
+}
+
+// Get all authors whose name or aliases contain the name filter
+func (a AuthorModel) GetAll(name string, filters Filters) ([]*Author, Metadata, error) {
+	// the authors list only pages by offset, so just the ORDER BY is needed
+	orderBy, _, _, _, err := filters.keyset(authorSortExpressions, 1)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) OVER(), id, name, aliases, bio, birth_year, death_year, created_at, version
+		FROM authors
+		WHERE (name ILIKE '%%' || $1 || '%%'
+		       OR EXISTS (SELECT 1 FROM unnest(aliases) AS alias WHERE alias ILIKE '%%' || $1 || '%%')
+		       OR $1 = '')
+		ORDER BY %s
+		LIMIT $2 OFFSET $3`, orderBy)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := a.DB.QueryContext(ctx, query, name, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+	totalRecords := 0
+
+	authors := []*Author{}
+
+	for rows.Next() {
+		var author Author
+		err := rows.Scan(
+			&totalRecords,
+			&author.ID,
+			&author.Name,
+			pq.Array(&author.Aliases),
+			&author.Bio,
+			&author.BirthYear,
+			&author.DeathYear,
+			&author.CreatedAt,
+			&author.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		authors = append(authors, &author)
+	}
+
+	// check for errors from iterating over rows
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return authors, metadata, nil
+}
+
+// Get up to `limit` authors for an autocomplete box. Names and aliases that
+// start with the prefix come first, then names that are merely similar so a
+// typo like "einstien" still finds "Einstein". Like the fuzzy quote filters
+// this uses <% so the trigram index on the name is used
+func (a AuthorModel) Suggest(prefix string, threshold float64, limit int) ([]*Author, error) {
+	query := `
+		SELECT id, name, aliases, bio, birth_year, death_year, created_at, version
+		FROM authors
+		WHERE name ILIKE $1 || '%' ESCAPE '\'
+		OR EXISTS (SELECT 1 FROM unnest(aliases) AS alias WHERE alias ILIKE $1 || '%' ESCAPE '\')
+		OR $2 <% name
+		ORDER BY name ILIKE $1 || '%' ESCAPE '\' DESC, word_similarity($2, name) DESC, name ASC
+		LIMIT $3
+		`
+
+	// % and _ typed by the user are not wildcards
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := a.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	// nothing was written so there is nothing to commit
+	defer tx.Rollback()
+
+	err = setWordSimilarityThreshold(ctx, tx, threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, query, escaped, prefix, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	authors := []*Author{}
+
+	for rows.Next() {
+		var author Author
+		err := rows.Scan(
+			&author.ID,
+			&author.Name,
+			pq.Array(&author.Aliases),
+			&author.Bio,
+			&author.BirthYear,
+			&author.DeathYear,
+			&author.CreatedAt,
+			&author.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		authors = append(authors, &author)
+	}
+
+	// check for errors from iterating over rows
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return authors, nil
+}
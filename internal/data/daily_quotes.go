@@ -0,0 +1,239 @@
+// Filename: internal/data/daily_quotes.go
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"hash/fnv"
+	"time"
+
+	"github.com/aiycoleman/qod/internal/validator"
+	"github.com/lib/pq"
+)
+
+// Layout used for the calendar day in URLs and JSON
+const DayLayout = "2006-01-02"
+
+// The quote selected (or pinned) for one calendar day
+type DailyQuote struct {
+	Day    time.Time `json:"-"`
+	Date   string    `json:"date"`   // the day formatted as YYYY-MM-DD
+	Pinned bool      `json:"pinned"` // chosen by an admin rather than the selector
+	Quote  *Quote    `json:"quote"`
+}
+
+// Check that a pin is for a day that has not started yet
+func ValidatePin(v *validator.Validator, day time.Time, today time.Time) {
+	v.Check(day.After(today), "date", validator.NewError("future_date"))
+}
+
+// The DailyQuoteModel expects a connection pool
+type DailyQuoteModel struct {
+	DB *sql.DB
+}
+
+// Truncate a time to the start of its calendar day in UTC
+func StartOfDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// Build a stable number from the day so every instance picks the same quote
+func daySeed(day time.Time) int64 {
+	h := fnv.New32a()
+	h.Write([]byte(day.Format(DayLayout)))
+	return int64(h.Sum32())
+}
+
+// Get the quote for a day, selecting one first if nothing is stored yet.
+// A quote used within `window` days either side of the day is not picked
+// again unless every quote has been used in that window.
+func (d DailyQuoteModel) GetForDay(day time.Time, window int) (*DailyQuote, error) {
+	day = StartOfDay(day)
+
+	dailyQuote, err := d.get(day)
+	if err == nil {
+		return dailyQuote, nil
+	}
+	if !errors.Is(err, ErrRecordNotFound) {
+		return nil, err
+	}
+
+	err = d.selectForDay(day, window)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.get(day)
+}
+
+// Read the stored quote for a day. A quote that has since gone back to
+// moderation or into the trash doesn't count, the day gets a new one
+func (d DailyQuoteModel) get(day time.Time) (*DailyQuote, error) {
+	query := `
+		SELECT d.day, d.pinned, q.id, q.content, q.author, q.author_id, q.tags, q.created_at, q.updated_at, q.views, q.version, q.status, q.moderation_reason, u.id, u.username
+		FROM daily_quotes d
+		INNER JOIN quotes q ON q.id = d.quote_id
+		LEFT JOIN users u ON u.id = q.user_id
+		WHERE d.day = $1 AND q.status = 'approved' AND q.deleted_at IS NULL
+		`
+	var dailyQuote DailyQuote
+	var quote Quote
+	var submitterID sql.NullInt64
+	var submitterName sql.NullString
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := d.DB.QueryRowContext(ctx, query, day).Scan(
+		&dailyQuote.Day,
+		&dailyQuote.Pinned,
+		&quote.ID,
+		&quote.Content,
+		&quote.Author,
+		&quote.AuthorID,
+		pq.Array(&quote.Tags),
+		&quote.CreatedAt,
+		&quote.UpdatedAt,
+		&quote.Views,
+		&quote.Version,
+		&quote.Status,
+		&quote.ModerationReason,
+		&submitterID,
+		&submitterName,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	quote.SubmittedBy = newSubmitter(submitterID, submitterName)
+	dailyQuote.Date = dailyQuote.Day.Format(DayLayout)
+	dailyQuote.Quote = &quote
+
+	return &dailyQuote, nil
+}
+
+// Pick a quote for the day and store it. If another instance stored one
+// first, the ON CONFLICT keeps theirs so everybody sees the same quote.
+func (d DailyQuoteModel) selectForDay(day time.Time, window int) error {
+	// candidates are quotes that were not shown near this day, falling
+	// back to every quote once the window has used them all up
+	query := `
+		WITH recent AS (
+			SELECT quote_id FROM daily_quotes
+			WHERE day BETWEEN $1::date - $2::int AND $1::date + $2::int
+			AND day <> $1
+		), candidates AS (
+			SELECT id FROM quotes WHERE status = 'approved' AND deleted_at IS NULL AND id NOT IN (SELECT quote_id FROM recent)
+		), pool AS (
+			SELECT id FROM candidates
+			UNION ALL
+			SELECT id FROM quotes WHERE status = 'approved' AND deleted_at IS NULL AND NOT EXISTS (SELECT 1 FROM candidates)
+		)
+		INSERT INTO daily_quotes (day, quote_id)
+		SELECT $1, id FROM pool
+		ORDER BY id
+		OFFSET $3 % GREATEST((SELECT COUNT(*) FROM pool), 1)
+		LIMIT 1
+		ON CONFLICT (day) DO NOTHING
+		`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// does nothing once the transaction is committed
+	defer tx.Rollback()
+
+	// a stored quote that can't be shown any more would otherwise keep
+	// the day from getting a new one
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM daily_quotes d
+		USING quotes q
+		WHERE d.day = $1 AND q.id = d.quote_id AND (q.status <> 'approved' OR q.deleted_at IS NOT NULL)
+		`, day)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, query, day, window, daySeed(day))
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	// nothing inserted and nothing there already means there are no quotes
+	if rowsAffected == 0 {
+		_, err = d.get(day)
+		return err
+	}
+
+	return nil
+}
+
+// Pin a quote to a day, replacing whatever was there before
+func (d DailyQuoteModel) Pin(day time.Time, quoteID int64) (*DailyQuote, error) {
+	day = StartOfDay(day)
+
+	query := `
+		INSERT INTO daily_quotes (day, quote_id, pinned)
+		VALUES ($1, $2, true)
+		ON CONFLICT (day) DO UPDATE
+		SET quote_id = EXCLUDED.quote_id, pinned = true, created_at = NOW()
+		`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := d.DB.ExecContext(ctx, query, day, quoteID)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.get(day)
+}
+
+// Remove a pin from a future day so the selector picks on the day itself
+func (d DailyQuoteModel) Unpin(day time.Time) error {
+	query := `
+		DELETE FROM daily_quotes
+		WHERE day = $1 AND pinned = true
+		`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := d.DB.ExecContext(ctx, query, StartOfDay(day))
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
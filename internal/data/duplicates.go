@@ -0,0 +1,108 @@
+// Filename: internal/data/duplicates.go
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// A quote that reads a lot like another one
+type SimilarQuote struct {
+	ID         int64   `json:"id"`
+	Content    string  `json:"content"`
+	Author     string  `json:"author"`
+	Similarity float32 `json:"similarity"`
+}
+
+// Get the id of the other quote with the same fingerprint as this one.
+// Quotes in the trash or rejected by a moderator don't count
+func (q QuoteModel) GetDuplicateID(quote *Quote) (int64, error) {
+	query := `
+		SELECT id
+		FROM quotes
+		WHERE fingerprint = $1 AND id <> $2 AND deleted_at IS NULL AND status <> 'rejected'
+		`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var id int64
+	err := q.DB.QueryRowContext(ctx, query, Fingerprint(quote.Content, quote.Author), quote.ID).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrRecordNotFound
+		default:
+			return 0, err
+		}
+	}
+	return id, nil
+}
+
+// Get up to `limit` other approved quotes whose content has at least the
+// threshold trigram similarity to this quote's, most similar first. Quotes
+// waiting for (or failing) moderation aren't public so they are left out
+func (q QuoteModel) GetSimilar(quote *Quote, threshold float64, limit int) ([]*SimilarQuote, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	// the threshold has to be set in the same transaction
+	tx, err := q.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	// nothing was written so there is nothing to commit
+	defer tx.Rollback()
+
+	err = setSimilarityThreshold(ctx, tx, threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	return getSimilar(ctx, tx, quote, threshold, limit)
+}
+
+// Anything that can run a query, a *sql.DB or a *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// The % operator lets the trigram index find the candidates, it compares
+// against the threshold set by setSimilarityThreshold (pg_trgm's default of
+// 0.3 otherwise), so that has to be set on db first
+func getSimilar(ctx context.Context, db queryer, quote *Quote, threshold float64, limit int) ([]*SimilarQuote, error) {
+	query := `
+		SELECT id, content, author, similarity(content, $1) AS similarity
+		FROM quotes
+		WHERE content % $1 AND similarity(content, $1) >= $2 AND id <> $3 AND status = 'approved' AND deleted_at IS NULL
+		ORDER BY similarity DESC, id ASC
+		LIMIT $4
+		`
+
+	rows, err := db.QueryContext(ctx, query, quote.Content, threshold, quote.ID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	similar := []*SimilarQuote{}
+
+	for rows.Next() {
+		var s SimilarQuote
+		err := rows.Scan(&s.ID, &s.Content, &s.Author, &s.Similarity)
+		if err != nil {
+			return nil, err
+		}
+
+		similar = append(similar, &s)
+	}
+
+	// check for errors from iterating over rows
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return similar, nil
+}
@@ -4,7 +4,18 @@ package data
 
 import (
 	"errors"
+
+	"github.com/lib/pq"
 )
 
 var ErrRecordNotFound = errors.New("record not found")
 var ErrEditConflict = errors.New("edit Conflict")
+var ErrDuplicateAuthor = errors.New("duplicate author")
+var ErrDuplicateQuote = errors.New("duplicate quote")
+
+// Check if the error is PostgreSQL rejecting a duplicate value for the
+// named unique constraint (or unique index)
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == constraint
+}
@@ -0,0 +1,92 @@
+// Filename: internal/data/exports.go
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Go through every quote matching the criteria in the order of the sort,
+// calling fn for each one as it is read instead of loading them all. The
+// quotes come from a read-only repeatable read transaction so they are a
+// consistent snapshot even if quotes change while the export runs. An
+// error from fn stops the export and is returned
+func (q QuoteModel) Export(criteria QuoteCriteria, filters Filters, fn func(*Quote) error) error {
+	conditions, args := criteria.conditions()
+
+	// exports are never paged so only the ORDER BY is needed
+	orderBy, _, _, _, err := filters.keyset(quoteSortExpressions, len(args)+1)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`
+        SELECT q.id, q.created_at, q.updated_at, q.views, q.content, q.author, q.author_id, q.tags, q.version, q.status, q.moderation_reason, u.id, u.username
+        FROM quotes q
+        LEFT JOIN users u ON u.id = q.user_id
+        WHERE %s
+        ORDER BY %s`,
+		conditions,
+		orderBy)
+
+	// the whole catalogue takes a lot longer than a page
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	tx, err := q.DB.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	// nothing was written so there is nothing to commit
+	defer tx.Rollback()
+
+	if criteria.Fuzzy {
+		err = setWordSimilarityThreshold(ctx, tx, criteria.Threshold)
+		if err != nil {
+			return err
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var quote Quote
+		var submitterID sql.NullInt64
+		var submitterName sql.NullString
+		err := rows.Scan(
+			&quote.ID,
+			&quote.CreatedAt,
+			&quote.UpdatedAt,
+			&quote.Views,
+			&quote.Content,
+			&quote.Author,
+			&quote.AuthorID,
+			pq.Array(&quote.Tags),
+			&quote.Version,
+			&quote.Status,
+			&quote.ModerationReason,
+			&submitterID,
+			&submitterName,
+		)
+		if err != nil {
+			return err
+		}
+		quote.SubmittedBy = newSubmitter(submitterID, submitterName)
+
+		err = fn(&quote)
+		if err != nil {
+			return err
+		}
+	}
+
+	// check for errors from iterating over rows
+	return rows.Err()
+}
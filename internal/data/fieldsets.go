@@ -0,0 +1,48 @@
+// Filename: internal/data/fieldsets.go
+package data
+
+import (
+	"slices"
+
+	"github.com/aiycoleman/qod/internal/validator"
+)
+
+// The fields of each resource a client can pick with ?fields=
+var (
+	QuoteFieldsSafeList = []string{
+		"id", "content", "author", "author_id", "tags", "submitted_by",
+		"created_at", "updated_at", "views", "version", "status",
+	}
+	AuthorFieldsSafeList = []string{"id", "name", "aliases", "bio", "birth_year", "death_year", "version"}
+)
+
+// The related records that can be embedded in a quote with ?include=.
+// author swaps the author name for the full author and tags swaps the tag
+// names for the tags with their quote counts
+var QuoteIncludeSafeList = []string{"author", "tags"}
+
+// Which fields to send back and which related records to embed. Empty
+// lists mean the usual representation
+type Fieldset struct {
+	Fields          []string // only these fields
+	Include         []string // related records to embed
+	FieldsSafeList  []string // allowed fields
+	IncludeSafeList []string // allowed related records
+}
+
+func ValidateFieldset(v *validator.Validator, f Fieldset) {
+	v.Check(validator.PermittedValues(f.Fields, f.FieldsSafeList...), "fields", validator.NewError("invalid_value"))
+	v.Check(validator.Unique(f.Fields), "fields", validator.NewError("duplicate_values"))
+	v.Check(validator.PermittedValues(f.Include, f.IncludeSafeList...), "include", validator.NewError("invalid_value"))
+	v.Check(validator.Unique(f.Include), "include", validator.NewError("duplicate_values"))
+}
+
+// Check if the client asked for a related record to be embedded
+func (f Fieldset) Includes(relation string) bool {
+	return slices.Contains(f.Include, relation)
+}
+
+// Check if the response is just the usual representation
+func (f Fieldset) IsEmpty() bool {
+	return len(f.Fields) == 0 && len(f.Include) == 0
+}
@@ -2,25 +2,54 @@
 package data
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/aiycoleman/qod/internal/validator"
 )
 
 type Filters struct {
-	Page         int // page number the client wants
-	PageSize     int // number of records per page
-	Sort         string
+	Page         int      // page number the client wants
+	PageSize     int      // number of records per page
+	Sort         string   // one or more comma-separated sort fields, e.g. "-created_at,author"
 	SortSafeList []string // allowed sort fields
+	Cursor       string   // opaque position to carry on from, replaces Page when set
 }
 
 func ValidateFilters(v *validator.Validator, f Filters) {
-	v.Check(f.Page > 0, "page", "must be greater than zero")
-	v.Check(f.Page <= 500, "page", "must be a maximum of 500")
-	v.Check(f.PageSize > 0, "page_size", "must be greater than zero")
-	v.Check(f.PageSize <= 100, "page_size", "must be a maximum of 100")
+	v.Check(f.Page > 0, "page", validator.NewError("positive"))
+	v.Check(f.PageSize > 0, "page_size", validator.NewError("positive"))
+	v.Check(f.PageSize <= 100, "page_size", validator.NewError("max_value", "max", 100))
 
-	v.Check(validator.PermittedValue(f.Sort, f.SortSafeList...), "sort", "invalid sort value")
+	// every field has to be allowed and each column can only be used once
+	sortIsSafe := true
+	var columns []string
+	for _, field := range strings.Split(f.Sort, ",") {
+		sortIsSafe = sortIsSafe && validator.PermittedValue(field, f.SortSafeList...)
+		columns = append(columns, strings.TrimPrefix(field, "-"))
+	}
+	v.Check(sortIsSafe, "sort", validator.NewError("invalid_value"))
+	v.Check(validator.Unique(columns), "sort", validator.NewError("duplicate_values"))
+	if !sortIsSafe {
+		// the cursor check below needs a usable sort
+		return
+	}
+
+	// deep offsets get slow, so past page 500 clients have to use cursors
+	if f.Cursor == "" {
+		v.Check(f.Page <= 500, "page", validator.NewError("max_value", "max", 500))
+		return
+	}
+
+	v.Check(f.Page == 1, "page", validator.NewError("conflicts_with", "field", "cursor"))
+	_, err := f.decodeCursor()
+	v.Check(err == nil, "cursor", validator.NewError("invalid_cursor"))
 }
 
 // Calculate how many records to send back
@@ -31,16 +60,21 @@ func (f Filters) limit() int {
 // Calculate the offset so that we remember how many records have been sent
 // and how many remain to be sent
 func (f Filters) offset() int {
+	// the cursor already skips the records that were sent
+	if f.Cursor != "" {
+		return 0
+	}
 	return (f.Page - 1) * f.PageSize
 }
 
 // Define a type to hold the metadata
 type Metadata struct {
-	CurrentPage  int `json:"current_page,omitempty"`
-	PageSize     int `json:"page_size,omitempty"`
-	FirstPage    int `json:"first_page,omitempty"`
-	LastPage     int `json:"last_page,omitempty"`
-	TotalRecords int `json:"total_records,omitempty"`
+	CurrentPage  int    `json:"current_page,omitempty"`
+	PageSize     int    `json:"page_size,omitempty"`
+	FirstPage    int    `json:"first_page,omitempty"`
+	LastPage     int    `json:"last_page,omitempty"`
+	TotalRecords int    `json:"total_records,omitempty"`
+	NextCursor   string `json:"next_cursor,omitempty"` // pass back as ?cursor= for the next page
 }
 
 // Calculate the Metadata
@@ -58,21 +92,158 @@ func calculateMetadata(totalRecords int, currentPage int, pageSize int) Metadata
 	}
 }
 
-// Sorting feature
-func (f Filters) sortColumn() string {
-	for _, safeValue := range f.SortSafeList {
-		if f.Sort == safeValue {
-			return strings.TrimPrefix(f.Sort, "-")
+// One column of the ORDER BY
+type sortKey struct {
+	column    string // the sort value without the "-"
+	direction string // ASC or DESC
+}
+
+// The columns to order by. Unless the sort already uses it, the id comes
+// last so that every row has a unique position, which keyset pagination needs
+func (f Filters) sortKeys() []sortKey {
+	var keys []sortKey
+	hasID := false
+
+	for _, field := range strings.Split(f.Sort, ",") {
+		if !slices.Contains(f.SortSafeList, field) {
+			// incase of SQL injection attack
+			panic("unsafe sort parameter: " + f.Sort)
+		}
+
+		key := sortKey{column: field, direction: "ASC"}
+		if strings.HasPrefix(field, "-") {
+			key = sortKey{column: field[1:], direction: "DESC"}
+		}
+		hasID = hasID || key.column == "id"
+		keys = append(keys, key)
+	}
+
+	if !hasID {
+		keys = append(keys, sortKey{column: "id", direction: "ASC"})
+	}
+	return keys
+}
+
+// What goes inside a cursor. The sort is kept so a cursor can't be used
+// with a different ordering than the one it came from
+type cursor struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"` // the last record's value for each sort key
+}
+
+// Build the cursor that continues after a record with the given sort values
+func (f Filters) encodeCursor(values []string) string {
+	js, _ := json.Marshal(cursor{Sort: f.Sort, Values: values})
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+// How to read a cursor value for the sort columns that aren't text. The
+// values were written by Postgres with ::text, so a value that doesn't parse
+// was not made by us and would only fail later as a bad SQL parameter
+var cursorValueParsers = map[string]func(string) (any, error){
+	"id":         parseCursorInteger,
+	"length":     parseCursorInteger,
+	"popularity": parseCursorInteger,
+	"created_at": parseCursorTime,
+	"updated_at": parseCursorTime,
+}
+
+func parseCursorInteger(value string) (any, error) {
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// Postgres writes a timestamptz as "2006-01-02 15:04:05+00", with minutes
+// in the offset for zones that need them
+func parseCursorTime(value string) (any, error) {
+	t, err := time.Parse("2006-01-02 15:04:05-07", value)
+	if err != nil {
+		t, err = time.Parse("2006-01-02 15:04:05-07:00", value)
+	}
+	return t, err
+}
+
+// Unpack the client's cursor into a value for each sort key, ready to be
+// used as query parameters
+func (f Filters) decodeCursor() ([]any, error) {
+	var c cursor
+
+	js, err := base64.RawURLEncoding.DecodeString(f.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(js, &c)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := f.sortKeys()
+	if c.Sort != f.Sort || len(c.Values) != len(keys) {
+		return nil, errors.New("cursor does not match the sort")
+	}
+
+	values := make([]any, len(keys))
+	for i, key := range keys {
+		parse, found := cursorValueParsers[key.column]
+		if !found {
+			values[i] = c.Values[i]
+			continue
+		}
+
+		values[i], err = parse(c.Values[i])
+		if err != nil {
+			return nil, fmt.Errorf("cursor value for %s: %w", key.column, err)
 		}
 	}
-	// incase of SQL injection attack
-	panic("unsafe sort parameter: " + f.Sort)
+	return values, nil
 }
 
-// Get the sort order
-func (f Filters) sortDirection() string {
-	if strings.HasPrefix(f.Sort, "-") {
-		return "DESC"
+// Build the ORDER BY list, the expressions whose values go into the next
+// cursor, and (with a cursor) a WHERE condition that skips every record up
+// to and including the cursor's. expressions maps the sort columns to SQL
+// and firstArg is the number of the first $ placeholder free to use.
+func (f Filters) keyset(expressions map[string]string, firstArg int) (orderBy string, cursorColumns string, after string, args []any, err error) {
+	keys := f.sortKeys()
+
+	var order, columns []string
+	for _, key := range keys {
+		expression, found := expressions[key.column]
+		if !found {
+			// incase of SQL injection attack
+			panic("unsafe sort parameter: " + key.column)
+		}
+		order = append(order, expression+" "+key.direction)
+		columns = append(columns, expression+"::text")
+	}
+	orderBy = strings.Join(order, ", ")
+	cursorColumns = strings.Join(columns, ", ")
+
+	if f.Cursor == "" {
+		return orderBy, cursorColumns, "TRUE", nil, nil
+	}
+
+	args, err = f.decodeCursor()
+	if err != nil {
+		return "", "", "", nil, err
+	}
+
+	// (a > x) OR (a = x AND b > y) OR (a = x AND b = y AND c > z) ...
+	// with < instead of > for the DESC keys
+	var alternatives []string
+	for i, key := range keys {
+		var parts []string
+		for j := 0; j < i; j++ {
+			parts = append(parts, fmt.Sprintf("%s = $%d", expressions[keys[j].column], firstArg+j))
+		}
+
+		comparison := ">"
+		if key.direction == "DESC" {
+			comparison = "<"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s $%d", expressions[key.column], comparison, firstArg+i))
+
+		alternatives = append(alternatives, "("+strings.Join(parts, " AND ")+")")
 	}
-	return "ASC"
+
+	return orderBy, cursorColumns, "(" + strings.Join(alternatives, " OR ") + ")", args, nil
 }
@@ -0,0 +1,108 @@
+// Filename: internal/data/filters_test.go
+package data
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aiycoleman/qod/internal/validator"
+)
+
+func TestKeyset(t *testing.T) {
+	expressions := map[string]string{"id": "q.id", "author": "q.author"}
+	safeList := []string{"id", "author", "-id", "-author"}
+
+	page := Filters{Page: 1, PageSize: 10, Sort: "-author", SortSafeList: safeList}
+
+	orderBy, cursorColumns, after, args, err := page.keyset(expressions, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if orderBy != "q.author DESC, q.id ASC" {
+		t.Errorf("unexpected order by: %q", orderBy)
+	}
+	if cursorColumns != "q.author::text, q.id::text" {
+		t.Errorf("unexpected cursor columns: %q", cursorColumns)
+	}
+	if after != "TRUE" || len(args) != 0 {
+		t.Errorf("expected no cursor condition, got: %q %v", after, args)
+	}
+
+	// carry on after the last record of the first page
+	next := page
+	next.Cursor = page.encodeCursor([]string{"Seneca", "42"})
+
+	v := validator.New()
+	ValidateFilters(v, next)
+	if !v.IsEmpty() {
+		t.Fatalf("unexpected validation errors: %v", v.Errors)
+	}
+
+	_, _, after, args, err = next.keyset(expressions, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "((q.author < $3) OR (q.author = $3 AND q.id > $4))"
+	if after != want {
+		t.Errorf("expected: %q, got: %q", want, after)
+	}
+	if len(args) != 2 || args[0] != "Seneca" || args[1] != int64(42) {
+		t.Errorf("unexpected args: %v", args)
+	}
+
+	// a value that doesn't fit its column would only fail in the query
+	tampered := page
+	tampered.Cursor = page.encodeCursor([]string{"Seneca", "abc"})
+	v = validator.New()
+	ValidateFilters(v, tampered)
+	if _, exists := v.Errors["cursor"]; !exists {
+		t.Error("expected a cursor error for a tampered id")
+	}
+
+	// a cursor only works with the sort it was made for
+	next.Sort = "author"
+	v = validator.New()
+	ValidateFilters(v, next)
+	if _, exists := v.Errors["cursor"]; !exists {
+		t.Error("expected a cursor error when the sort changes")
+	}
+}
+
+func TestSortKeys(t *testing.T) {
+	safeList := []string{"id", "author", "created_at", "-id", "-author", "-created_at"}
+
+	orderBy, _, _, _, err := Filters{Sort: "-created_at,author", SortSafeList: safeList}.keyset(
+		map[string]string{"id": "q.id", "author": "q.author", "created_at": "q.created_at"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "q.created_at DESC, q.author ASC, q.id ASC"
+	if orderBy != want {
+		t.Errorf("expected: %q, got: %q", want, orderBy)
+	}
+
+	// the same column twice is ambiguous
+	v := validator.New()
+	ValidateFilters(v, Filters{Page: 1, PageSize: 10, Sort: "author,-author", SortSafeList: safeList})
+	if _, exists := v.Errors["sort"]; !exists {
+		t.Error("expected a sort error for a repeated column")
+	}
+}
+
+func TestParseCursorTime(t *testing.T) {
+	for _, value := range []string{"2026-10-18 01:49:05+00", "2026-10-18 07:19:05+05:30"} {
+		got, err := parseCursorTime(value)
+		if err != nil {
+			t.Errorf("parseCursorTime(%q): %v", value, err)
+			continue
+		}
+		if !got.(time.Time).Equal(time.Date(2026, 10, 18, 1, 49, 5, 0, time.UTC)) {
+			t.Errorf("parseCursorTime(%q): got %v", value, got)
+		}
+	}
+
+	_, err := parseCursorTime("yesterday")
+	if err == nil {
+		t.Error("expected an error for a value that isn't a timestamp")
+	}
+}
@@ -0,0 +1,136 @@
+// Filename: internal/data/fingerprint.go
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/lib/pq"
+	"golang.org/x/text/unicode/norm"
+)
+
+// The quote text the way we compare it for duplicates: NFC, lowercase,
+// without punctuation or symbols (so curly and straight quotes, dashes and
+// full stops don't matter) and with single spaces between words
+func NormalizeText(s string) string {
+	s = strings.ToLower(norm.NFC.String(s))
+
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			return -1
+		}
+		return r
+	}, s)
+
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// A fixed length value that is the same for quotes that only differ in
+// case, spacing or punctuation. Quotes saved before the column was added
+// get theirs from RecomputeFingerprints
+func Fingerprint(content string, author string) string {
+	sum := sha256.Sum256([]byte(NormalizeText(content) + "|" + NormalizeText(author)))
+	return hex.EncodeToString(sum[:])
+}
+
+// Work out the fingerprint of every quote again with Fingerprint. The SQL
+// in a migration can't normalise text the same way Go does, so existing
+// quotes are filled in (or fixed) by running this. As when the column was
+// added, the oldest of any quotes that are duplicates keeps the fingerprint
+// and the others are left without one. Returns how many quotes changed
+func (q QuoteModel) RecomputeFingerprints(timeout time.Duration) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	tx, err := q.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	// does nothing once the transaction is committed
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, content, author, fingerprint, deleted_at IS NULL AND status <> 'rejected'
+		FROM quotes
+		ORDER BY id
+		FOR UPDATE
+		`)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	// only quotes that aren't in the trash or rejected have to be unique
+	taken := make(map[string]bool)
+	var changed []int64
+	var ids []int64
+	var fingerprints []string
+
+	for rows.Next() {
+		var id int64
+		var content, author string
+		var current sql.NullString
+		var active bool
+		err = rows.Scan(&id, &content, &author, &current, &active)
+		if err != nil {
+			return 0, err
+		}
+
+		fingerprint := Fingerprint(content, author)
+		keep := true
+		if active {
+			keep = !taken[fingerprint]
+			taken[fingerprint] = true
+		}
+
+		if keep && current.Valid && current.String == fingerprint {
+			continue
+		}
+		if !keep && !current.Valid {
+			continue
+		}
+
+		changed = append(changed, id)
+		if keep {
+			ids = append(ids, id)
+			fingerprints = append(fingerprints, fingerprint)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+
+	if len(changed) == 0 {
+		return 0, nil
+	}
+
+	// clear the old values first so the unique index doesn't trip over a
+	// fingerprint moving from one quote to another
+	_, err = tx.ExecContext(ctx, `UPDATE quotes SET fingerprint = NULL WHERE id = ANY($1)`, pq.Array(changed))
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE quotes
+		SET fingerprint = f.fingerprint
+		FROM unnest($1::bigint[], $2::text[]) AS f(id, fingerprint)
+		WHERE quotes.id = f.id
+		`, pq.Array(ids), pq.Array(fingerprints))
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return len(changed), nil
+}
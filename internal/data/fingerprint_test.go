@@ -0,0 +1,28 @@
+// Filename: internal/data/fingerprint_test.go
+package data
+
+import "testing"
+
+func TestFingerprint(t *testing.T) {
+	want := Fingerprint("Stay hungry, stay foolish.", "Steve Jobs")
+
+	same := []struct{ content, author string }{
+		{"stay hungry stay foolish", "steve jobs"},
+		{"  Stay   hungry —  stay foolish!  ", "Steve  Jobs"},
+		{"“Stay hungry, stay foolish.”", "Steve Jobs."},
+	}
+	for _, q := range same {
+		if got := Fingerprint(q.content, q.author); got != want {
+			t.Errorf("expected %q by %q to match", q.content, q.author)
+		}
+	}
+
+	// "é" written as e plus a combining accent is the same after NFC
+	if Fingerprint("Café", "A") != Fingerprint("Café", "A") {
+		t.Error("expected composed and decomposed text to match")
+	}
+
+	if Fingerprint("Stay hungry", "Steve Jobs") == want {
+		t.Error("expected different content not to match")
+	}
+}
@@ -0,0 +1,202 @@
+// Filename: internal/data/imports.go
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// The most quotes a single import can hold
+const MaxImportSize = 1000
+
+// What happened to one quote of an import
+type ImportResult struct {
+	DuplicateOf int64           // the quote with the same fingerprint, 0 if it was inserted
+	Similar     []*SimilarQuote // quotes that read a lot like the inserted one
+}
+
+// Insert a batch of validated quotes in one transaction, so either all of
+// them are added or none are. A quote with the same fingerprint as an
+// existing quote, or an earlier quote in the batch, is skipped, even when
+// the other quote is added while the import runs. Inserted quotes are
+// checked for similar quotes using the threshold
+func (q QuoteModel) Import(quotes []*Quote, threshold float64) ([]ImportResult, error) {
+	// much more work than a single insert
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := q.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	// does nothing once the transaction is committed
+	defer tx.Rollback()
+
+	// for the similar quotes check of each inserted quote
+	err = setSimilarityThreshold(ctx, tx, threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ImportResult, len(quotes))
+
+	for i, quote := range quotes {
+		quote.Content = strings.TrimSpace(quote.Content)
+		quote.Author = strings.TrimSpace(quote.Author)
+
+		// an alias becomes the author's name, as it does for a single
+		// quote, so the fingerprint matches the same quote added that way
+		quote.AuthorID, quote.Author, err = findAuthorTx(ctx, tx, quote.Author)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		quote.Fingerprint = Fingerprint(quote.Content, quote.Author)
+
+		// earlier quotes of the batch are already in the table
+		err = tx.QueryRowContext(ctx, `
+			SELECT id
+			FROM quotes
+			WHERE fingerprint = $1 AND deleted_at IS NULL AND status <> 'rejected'`, quote.Fingerprint).Scan(&results[i].DuplicateOf)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+
+		// the same quote may be added while we import it, undoing back to
+		// here drops the author we might add for it
+		_, err = tx.ExecContext(ctx, `SAVEPOINT import_quote`)
+		if err != nil {
+			return nil, err
+		}
+
+		// a new author is only added for a quote that is kept
+		err = setQuoteAuthorTx(ctx, tx, quote)
+		if err != nil {
+			return nil, err
+		}
+
+		var userID *int64
+		if quote.SubmittedBy != nil {
+			userID = &quote.SubmittedBy.ID
+		}
+		// a nil slice would be stored as NULL instead of no tags
+		if quote.Tags == nil {
+			quote.Tags = []string{}
+		}
+		if quote.Status == "" {
+			quote.Status = StatusPending
+		}
+
+		// nothing is returned if the quote was added since we checked
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO quotes (content, author, author_id, tags, user_id, fingerprint, status)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (fingerprint) WHERE deleted_at IS NULL AND status <> 'rejected' DO NOTHING
+			RETURNING id, created_at, updated_at, version`,
+			quote.Content, quote.Author, quote.AuthorID, pq.Array(quote.Tags), userID, quote.Fingerprint, quote.Status,
+		).Scan(&quote.ID, &quote.CreatedAt, &quote.UpdatedAt, &quote.Version)
+		if errors.Is(err, sql.ErrNoRows) {
+			_, err = tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT import_quote`)
+			if err != nil {
+				return nil, err
+			}
+			err = tx.QueryRowContext(ctx, `
+				SELECT id
+				FROM quotes
+				WHERE fingerprint = $1 AND deleted_at IS NULL AND status <> 'rejected'`, quote.Fingerprint).Scan(&results[i].DuplicateOf)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.ExecContext(ctx, `RELEASE SAVEPOINT import_quote`)
+		if err != nil {
+			return nil, err
+		}
+
+		err = writeRevision(ctx, tx, quote.ID, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		results[i].Similar, err = getSimilar(ctx, tx, quote, threshold, 5)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// Point a quote without an author id at the author whose name or alias
+// matches its author string, adding a new author if there is none. The
+// author string is replaced by the author's name so "A. Einstein" is shown
+// as "Albert Einstein"
+func setQuoteAuthorTx(ctx context.Context, tx *sql.Tx, quote *Quote) error {
+	if quote.AuthorID != 0 {
+		return nil
+	}
+
+	var err error
+	quote.AuthorID, quote.Author, err = getOrCreateAuthorTx(ctx, tx, quote.Author)
+	return err
+}
+
+// Find the author with the name (or alias) inside a transaction, returning
+// their id and name. sql.ErrNoRows means there is no such author, the name
+// is returned unchanged then
+func findAuthorTx(ctx context.Context, tx *sql.Tx, name string) (int64, string, error) {
+	query := `
+		SELECT id, name
+		FROM authors
+		WHERE lower(name) = lower($1)
+		OR EXISTS (SELECT 1 FROM unnest(aliases) AS alias WHERE lower(alias) = lower($1))
+		ORDER BY lower(name) = lower($1) DESC, id ASC
+		LIMIT 1
+		`
+
+	var id int64
+	var authorName string
+	err := tx.QueryRowContext(ctx, query, name).Scan(&id, &authorName)
+	if err != nil {
+		return 0, name, err
+	}
+	return id, authorName, nil
+}
+
+// Find the author with the name (or alias) inside a transaction, adding
+// them if they are new. Returns the author's id and name, which is what
+// the quote's author string should be
+func getOrCreateAuthorTx(ctx context.Context, tx *sql.Tx, name string) (int64, string, error) {
+	id, authorName, err := findAuthorTx(ctx, tx, name)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return id, authorName, err
+	}
+
+	// somebody may add the same author at the same time, in which case
+	// our insert does nothing and we look them up again
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO authors (name)
+		VALUES ($1)
+		ON CONFLICT DO NOTHING
+		RETURNING id`, name).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return findAuthorTx(ctx, tx, name)
+	}
+	return id, name, err
+}
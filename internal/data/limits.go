@@ -0,0 +1,32 @@
+// Filename: internal/data/limits.go
+package data
+
+import "github.com/aiycoleman/qod/internal/validator"
+
+// The longest each text field can be. Lengths are counted in characters
+// the way a reader counts them, so quotes in accented or non-Latin
+// scripts get the same room as English ones
+type TextLimits struct {
+	Content  int // quote content
+	Author   int // quote author, and author names and aliases
+	Bio      int // author biography
+	Username int
+}
+
+// The limits used unless the flags say otherwise
+var DefaultTextLimits = TextLimits{
+	Content:  100,
+	Author:   25,
+	Bio:      1000,
+	Username: 200,
+}
+
+// The checks every required single line text field gets. Clients' text is
+// expected to have gone through validator.CleanText first
+func validateText(v *validator.Validator, key string, value string, max int) {
+	v.Check(validator.NotBlank(value), key, validator.NewError("required"))
+	v.Check(validator.MaxChars(value, max), key, validator.NewError("max_chars", "max", max))
+	v.Check(validator.IsNFC(value), key, validator.NewError("not_nfc"))
+	v.Check(validator.IsTrimmed(value), key, validator.NewError("untrimmed"))
+	v.Check(validator.NoControlCharacters(value), key, validator.NewError("control_characters"))
+}
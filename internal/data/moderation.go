@@ -0,0 +1,63 @@
+// Filename: internal/data/moderation.go
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/aiycoleman/qod/internal/validator"
+)
+
+// Check a moderator's decision. A rejection has to say why so the
+// submitter knows what to fix
+func ValidateModeration(v *validator.Validator, quote *Quote) {
+	v.Check(validator.PermittedValue(quote.Status, StatusApproved, StatusRejected), "status", validator.NewError("not_permitted", "values", "approved, rejected"))
+	v.Check(quote.Status != StatusRejected || quote.ModerationReason != "", "reason", validator.NewError("required_to_reject"))
+	v.Check(len(quote.ModerationReason) <= 500, "reason", validator.NewError("max_bytes", "max", 500))
+}
+
+// Save a moderator's decision on a quote, as long as it is still at the
+// version we read. A quote that is no longer approved can't stay the quote
+// of the day, today or later. A rejected quote that has since been added
+// again by somebody else can't be approved, ErrDuplicateQuote is returned
+func (q QuoteModel) Moderate(quote *Quote, moderatorID int64) error {
+	query := `
+        UPDATE quotes
+        SET status = $1, moderation_reason = $2, moderated_by = $3, moderated_at = NOW(), version = version + 1
+        WHERE id = $4 AND version = $5 AND deleted_at IS NULL
+        RETURNING version
+		`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := q.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// does nothing once the transaction is committed
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, quote.Status, quote.ModerationReason, moderatorID, quote.ID, quote.Version).Scan(&quote.Version)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err, "quotes_fingerprint_idx"):
+			return ErrDuplicateQuote
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	if quote.Status != StatusApproved {
+		_, err = tx.ExecContext(ctx, `DELETE FROM daily_quotes WHERE quote_id = $1 AND day >= CURRENT_DATE`, quote.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
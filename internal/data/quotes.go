@@ -6,30 +6,88 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"math/rand/v2"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aiycoleman/qod/internal/validator"
+	"github.com/lib/pq"
 )
 
+// Tags are short lowercase words, e.g. "motivation" or "self-help"
+var TagRX = regexp.MustCompile("^[a-z0-9]+(?:-[a-z0-9]+)*$")
+
 // Uppercase allows them to be exportable/public
 type Quote struct {
-	ID        int64     `json:"id"`      // unique value for each quote
-	Content   string    `json:"content"` // the quote data
-	Author    string    `json:"author"`  // the person who wrote the quote
-	CreatedAt time.Time `json:"-"`       // database timestamp
-	Version   int32     `json:"version"` // incremented on each update
+	ID               int64      `json:"id"`                          // unique value for each quote
+	Content          string     `json:"content"`                     // the quote data
+	Author           string     `json:"author"`                      // the person who wrote the quote
+	AuthorID         int64      `json:"author_id"`                   // the matching record in authors
+	Tags             []string   `json:"tags"`                        // used to group quotes (motivation, humor, tech...)
+	SubmittedBy      *Submitter `json:"submitted_by"`                // the user who added the quote, if known
+	CreatedAt        time.Time  `json:"created_at"`                  // database timestamp
+	UpdatedAt        time.Time  `json:"updated_at"`                  // when the quote was last edited
+	Views            int64      `json:"views"`                       // how many times the quote was displayed
+	Version          int32      `json:"version"`                     // incremented on each update
+	Status           string     `json:"status"`                      // pending, approved or rejected
+	ModerationReason string     `json:"moderation_reason,omitempty"` // why a moderator approved or rejected the quote
+	Fingerprint      string     `json:"-"`                           // the same for quotes that only differ in case, spacing or punctuation
+	DeletedAt        *time.Time `json:"deleted_at,omitempty"`        // when the quote was moved to the trash
+}
+
+// Where a quote is in moderation. Only approved quotes are shown publicly
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusRejected = "rejected"
+)
+
+// Every status a quote can have
+var QuoteStatuses = []string{StatusPending, StatusApproved, StatusRejected}
+
+// The public details of the user who submitted a quote
+type Submitter struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+// Build the submitter from a LEFT JOIN on users. Quotes added before we
+// tracked ownership (or whose user was deleted) have no submitter
+func newSubmitter(id sql.NullInt64, username sql.NullString) *Submitter {
+	if !id.Valid {
+		return nil
+	}
+	return &Submitter{ID: id.Int64, Username: username.String}
+}
+
+// Check if the user submitted the quote
+func (quote *Quote) IsOwnedBy(userID int64) bool {
+	return quote.SubmittedBy != nil && quote.SubmittedBy.ID == userID
 }
 
 // Performs the validation checks
-func ValidateQuote(v *validator.Validator, quote *Quote) {
-	// check if the Content field is empty
-	v.Check(quote.Content != "", "content", "must be provided")
-	// check if the Author field us empty
-	v.Check(quote.Author != "", "author", "must be provided")
-	// chekc if the content in the field is empty
-	v.Check(len(quote.Content) <= 100, "content", "must not be more than 100 bytes long")
-	// check if the Author field is empty
-	v.Check(len(quote.Author) <= 25, "author", "must not be more than 25 bytes long")
+func ValidateQuote(v *validator.Validator, quote *Quote, limits TextLimits) {
+	// the content and author must be provided, tidy and not too long
+	validateText(v, "content", quote.Content, limits.Content)
+	validateText(v, "author", quote.Author, limits.Author)
+	// tags are optional but must be sensible if given
+	v.Check(len(quote.Tags) <= 5, "tags", validator.NewError("max_items", "max", 5))
+	v.Check(validator.Unique(quote.Tags), "tags", validator.NewError("duplicate_values"))
+	for _, tag := range quote.Tags {
+		v.Check(len(tag) <= 20, "tags", validator.NewError("max_item_bytes", "max", 20))
+		v.Check(validator.Matches(tag, TagRX), "tags", validator.NewError("invalid_tag"))
+	}
+}
+
+// Trim and lowercase tags so "Humor " and "humor" are the same tag
+func NormalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		normalized = append(normalized, strings.ToLower(strings.TrimSpace(tag)))
+	}
+	return normalized
 }
 
 // The QuoteModel expects a connection pool
@@ -38,23 +96,69 @@ type QuoteModel struct {
 }
 
 // Insert a new row in the quotes table
-// A pointer to the quote
+// A pointer to the quote. A quote without an author id is matched to the
+// author with that name or alias (who is added if they are new) in the same
+// transaction, so a quote that isn't saved doesn't leave an author behind
 func (q QuoteModel) Insert(quote *Quote) error {
 	// SQL statement to be executed
 	query := `
-		INSERT INTO quotes (content, author)
-		VALUES ($1, $2)
-		RETURNING id, created_at, version
+		INSERT INTO quotes (content, author, author_id, tags, user_id, fingerprint, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at, version
 		`
-	// values to replace the $1 and $2
-	args := []any{quote.Content, quote.Author}
+	// the owner is optional
+	var userID *int64
+	if quote.SubmittedBy != nil {
+		userID = &quote.SubmittedBy.ID
+	}
+	// a nil slice would be stored as NULL instead of no tags
+	if quote.Tags == nil {
+		quote.Tags = []string{}
+	}
+	// quotes wait for a moderator unless told otherwise
+	if quote.Status == "" {
+		quote.Status = StatusPending
+	}
 
 	// Context with a 3-second timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
+	tx, err := q.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// does nothing once the transaction is committed
+	defer tx.Rollback()
+
+	err = setQuoteAuthorTx(ctx, tx, quote)
+	if err != nil {
+		return err
+	}
+
+	// the author's name is part of the fingerprint
+	quote.Fingerprint = Fingerprint(quote.Content, quote.Author)
+	// values to replace the $1 to $7
+	args := []any{quote.Content, quote.Author, quote.AuthorID, pq.Array(quote.Tags), userID, quote.Fingerprint, quote.Status}
+
 	// execute query against the database
-	return q.DB.QueryRowContext(ctx, query, args...).Scan(&quote.ID, &quote.CreatedAt, &quote.Version)
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&quote.ID, &quote.CreatedAt, &quote.UpdatedAt, &quote.Version)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err, "quotes_fingerprint_idx"):
+			return ErrDuplicateQuote
+		default:
+			return err
+		}
+	}
+
+	// the first version goes into the history too
+	err = writeRevision(ctx, tx, quote.ID, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 // Get a specific quote from the quote table
@@ -66,12 +170,15 @@ func (q QuoteModel) Get(id int64) (*Quote, error) {
 
 	// the SQL query to be executed against the database table
 	query := `
-		SELECT id, content, author, created_at, version
-		FROM quotes
-		WHERE id = $1
+		SELECT q.id, q.content, q.author, q.author_id, q.tags, q.created_at, q.updated_at, q.views, q.version, q.status, q.moderation_reason, u.id, u.username
+		FROM quotes q
+		LEFT JOIN users u ON u.id = q.user_id
+		WHERE q.id = $1 AND q.deleted_at IS NULL
 		`
 	// Declare a variable of type Quote to store the returned quote
 	var quote Quote
+	var submitterID sql.NullInt64
+	var submitterName sql.NullString
 
 	// Set a 3-second context/timer
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
@@ -80,8 +187,16 @@ func (q QuoteModel) Get(id int64) (*Quote, error) {
 	err := q.DB.QueryRowContext(ctx, query, id).Scan(&quote.ID,
 		&quote.Content,
 		&quote.Author,
+		&quote.AuthorID,
+		pq.Array(&quote.Tags),
 		&quote.CreatedAt,
-		&quote.Version)
+		&quote.UpdatedAt,
+		&quote.Views,
+		&quote.Version,
+		&quote.Status,
+		&quote.ModerationReason,
+		&submitterID,
+		&submitterName)
 
 	// check for which type error
 	if err != nil {
@@ -93,30 +208,114 @@ func (q QuoteModel) Get(id int64) (*Quote, error) {
 		}
 	}
 
+	quote.SubmittedBy = newSubmitter(submitterID, submitterName)
+
 	return &quote, nil
 }
 
-// Update a specific quote from the db
-func (q QuoteModel) Update(quote *Quote) error {
+// Update a specific quote from the db. The version number determines if the
+// update happens, if someone else changed the quote since we read it the
+// query fails and the client will need to try again. The new version is
+// saved in the quote's revisions along with the user who made it. The
+// status is saved too, since an edit may send the quote back to moderation.
+// A quote without an author id gets one the same way as in Insert
+func (q QuoteModel) Update(quote *Quote, editorID int64) error {
 	// The SQL query to be executed against the database table
 	// Every time we make an update, we increment the version number
 	query := `
         UPDATE quotes
-        SET content = $1, author = $2, version = version + 1
-        WHERE id = $3
-        RETURNING version
+        SET content = $1, author = $2, author_id = $3, tags = $4, fingerprint = $7, status = $8, moderation_reason = $9,
+            updated_at = NOW(), version = version + 1
+        WHERE id = $5 AND version = $6 AND deleted_at IS NULL
+        RETURNING updated_at, version
 		`
-	// values to replace the $1 and $2
-	args := []any{quote.Content, quote.Author, quote.ID}
+	// a nil slice would be stored as NULL instead of no tags
+	if quote.Tags == nil {
+		quote.Tags = []string{}
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	return q.DB.QueryRowContext(ctx, query, args...).Scan(&quote.Version)
+	tx, err := q.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// does nothing once the transaction is committed
+	defer tx.Rollback()
+
+	err = setQuoteAuthorTx(ctx, tx, quote)
+	if err != nil {
+		return err
+	}
+
+	// the author's name is part of the fingerprint
+	quote.Fingerprint = Fingerprint(quote.Content, quote.Author)
+	// values to replace the $1 to $9
+	args := []any{quote.Content, quote.Author, quote.AuthorID, pq.Array(quote.Tags), quote.ID, quote.Version, quote.Fingerprint, quote.Status, quote.ModerationReason}
+
+	// deleting and restoring bump the version without saving a revision,
+	// so make sure the version being replaced is in the history
+	err = writeRevision(ctx, tx, quote.ID, nil)
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&quote.UpdatedAt, &quote.Version)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err, "quotes_fingerprint_idx"):
+			return ErrDuplicateQuote
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	err = writeRevision(ctx, tx, quote.ID, &editorID)
+	if err != nil {
+		return err
+	}
+
+	// an edit that sends the quote back to moderation takes it off the
+	// quote of the day too, the same as Moderate does
+	if quote.Status != StatusApproved {
+		_, err = tx.ExecContext(ctx, `DELETE FROM daily_quotes WHERE quote_id = $1 AND day >= CURRENT_DATE`, quote.ID)
+		if err != nil {
+			return err
+		}
+	}
 
+	return tx.Commit()
 }
 
-// Delete a specific Quote
-func (q QuoteModel) Delete(id int64) error {
+// Add the views counted since the last call, a count for each quote id.
+// The version is left alone since this isn't an edit and shouldn't cause
+// edit conflicts. Quotes deleted in the meantime are skipped
+func (q QuoteModel) AddViews(views map[int64]int64) error {
+	ids := make([]int64, 0, len(views))
+	counts := make([]int64, 0, len(views))
+	for id, count := range views {
+		ids = append(ids, id)
+		counts = append(counts, count)
+	}
+
+	query := `
+        UPDATE quotes
+        SET views = quotes.views + v.count
+        FROM unnest($1::bigint[], $2::bigint[]) AS v(id, count)
+        WHERE quotes.id = v.id AND quotes.deleted_at IS NULL
+		`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := q.DB.ExecContext(ctx, query, pq.Array(ids), pq.Array(counts))
+	return err
+}
+
+// Move a quote to the trash, as long as it is still at the version we
+// read. It stays in the table until it is restored or purged
+func (q QuoteModel) Delete(id int64, version int32) error {
 
 	// check if the id is valid
 	if id < 1 {
@@ -125,13 +324,21 @@ func (q QuoteModel) Delete(id int64) error {
 
 	// the SQL query to be executed against the database table
 	query := `
-        DELETE FROM quotes
-        WHERE id = $1
+        UPDATE quotes
+        SET deleted_at = NOW(), version = version + 1
+        WHERE id = $1 AND version = $2 AND deleted_at IS NULL
       `
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	result, err := q.DB.ExecContext(ctx, query, id)
+	tx, err := q.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// does nothing once the transaction is committed
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query, id, version)
 	if err != nil {
 		return err
 	}
@@ -142,29 +349,159 @@ func (q QuoteModel) Delete(id int64) error {
 		return err
 	}
 
+	// either the quote is gone (or already in the trash) or it was changed
+	// since we read it
 	if rowsAffected == 0 {
-		return ErrRecordNotFound
+		var exists bool
+		err = tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM quotes WHERE id = $1 AND deleted_at IS NULL)`, id).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrRecordNotFound
+		}
+		return ErrEditConflict
+	}
+
+	// a deleted quote can't be the quote of the day, today or later. Past
+	// days are kept in case the quote is restored
+	_, err = tx.ExecContext(ctx, `DELETE FROM daily_quotes WHERE quote_id = $1 AND day >= CURRENT_DATE`, id)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return tx.Commit()
 }
 
-// Get all quotes
-func (q QuoteModel) GetAll(content string, author string, filters Filters) ([]*Quote, Metadata, error) {
+// What to look for when listing quotes. Empty fields match every quote
+type QuoteCriteria struct {
+	Content   string
+	Author    string
+	Fuzzy     bool     // match content and author by trigram similarity instead of words
+	Threshold float64  // how similar (0 to 1) a fuzzy match has to be
+	Tags      []string // quotes must have every one of these tags
+	UserID    int64    // only quotes submitted by this user, 0 means any user
+	Status    string   // only quotes with this moderation status
+	// only quotes created in this range, nil means no limit on that side
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+}
+
+// The SQL condition for matching a column against a text filter. Exact mode
+// matches whole words, fuzzy mode lets "einstien" find "Einstein". The <%
+// operator (unlike calling word_similarity) can use the trigram indexes, it
+// compares against the threshold set by setWordSimilarityThreshold
+func textMatchCondition(column string, param string, fuzzy bool) string {
+	if fuzzy {
+		return fmt.Sprintf("(%[2]s <%% %[1]s OR %[2]s = '')", column, param)
+	}
+	return fmt.Sprintf("(to_tsvector('simple', %[1]s) @@ plainto_tsquery('simple', %[2]s) OR %[2]s = '')", column, param)
+}
+
+// The WHERE conditions for the criteria and the values for their
+// placeholders, which start at $1
+func (c QuoteCriteria) conditions() (string, []any) {
+	args := []any{
+		c.Content,
+		c.Author,
+		pq.Array(c.Tags),
+		c.UserID,
+		c.CreatedAfter,
+		c.CreatedBefore,
+		c.Status,
+	}
+
+	conditions := fmt.Sprintf(`%s
+        AND %s
+        AND (q.tags @> $3 OR $3 = '{}')
+        AND (q.user_id = $4 OR $4 = 0)
+        AND (q.created_at >= $5 OR $5 IS NULL)
+        AND (q.created_at < $6 OR $6 IS NULL)
+        AND q.status = $7
+        AND q.deleted_at IS NULL`,
+		textMatchCondition("q.content", "$1", c.Fuzzy),
+		textMatchCondition("q.author", "$2", c.Fuzzy))
+
+	return conditions, args
+}
+
+// Set how similar (0 to 1) a word has to be for the <% operator to match,
+// until the end of the transaction. pg_trgm only takes the threshold as a
+// setting, and a transaction keeps it off the pool's other connections
+func setWordSimilarityThreshold(ctx context.Context, tx *sql.Tx, threshold float64) error {
+	_, err := tx.ExecContext(ctx, `SELECT set_config('pg_trgm.word_similarity_threshold', $1, true)`,
+		strconv.FormatFloat(threshold, 'f', -1, 64))
+	return err
+}
+
+// Set how similar (0 to 1) two texts have to be for the % operator to
+// match, until the end of the transaction. Like the word similarity
+// threshold it can only be given as a setting
+func setSimilarityThreshold(ctx context.Context, tx *sql.Tx, threshold float64) error {
+	_, err := tx.ExecContext(ctx, `SELECT set_config('pg_trgm.similarity_threshold', $1, true)`,
+		strconv.FormatFloat(threshold, 'f', -1, 64))
+	return err
+}
+
+// The SQL for each column the quotes list can be sorted by
+var quoteSortExpressions = map[string]string{
+	"id":         "q.id",
+	"author":     "q.author",
+	"created_at": "q.created_at",
+	"updated_at": "q.updated_at",
+	"length":     "char_length(q.content)",
+	"popularity": "q.views",
+}
+
+// Get all quotes matching the criteria. Besides the usual page metadata a
+// next cursor is returned whenever there are more quotes after this page
+func (q QuoteModel) GetAll(criteria QuoteCriteria, filters Filters) ([]*Quote, Metadata, error) {
+	conditions, args := criteria.conditions()
+	// one extra record tells us if there is a next page
+	limitArg := len(args) + 1
+	args = append(args, filters.limit()+1, filters.offset())
+
+	orderBy, cursorColumns, after, cursorArgs, err := filters.keyset(quoteSortExpressions, len(args)+1)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	args = append(args, cursorArgs...)
+
 	query := fmt.Sprintf(`
-        SELECT COUNT(*) OVER(), id, created_at, content, author, version
-        FROM quotes
-        WHERE (to_tsvector('simple', content) @@
-              plainto_tsquery('simple', $1) OR $1 = '') 
-        AND (to_tsvector('simple', author) @@ 
-             plainto_tsquery('simple', $2) OR $2 = '') 
-        ORDER BY %s %s, id ASC
-		LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+        SELECT COUNT(*) OVER(), q.id, q.created_at, q.updated_at, q.views, q.content, q.author, q.author_id, q.tags, q.version, q.status, q.moderation_reason, u.id, u.username, %s
+        FROM quotes q
+        LEFT JOIN users u ON u.id = q.user_id
+        WHERE %s
+        AND %s
+        ORDER BY %s
+		LIMIT $%d OFFSET $%d`,
+		cursorColumns,
+		conditions,
+		after,
+		orderBy,
+		limitArg, limitArg+1)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	rows, err := q.DB.QueryContext(ctx, query, content, author, filters.limit(), filters.offset())
+	// a fuzzy match needs its threshold set in the same transaction
+	var db queryer = q.DB
+	if criteria.Fuzzy {
+		tx, err := q.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		// nothing was written so there is nothing to commit
+		defer tx.Rollback()
+
+		err = setWordSimilarityThreshold(ctx, tx, criteria.Threshold)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		db = tx
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, Metadata{}, err
 	}
@@ -172,22 +509,50 @@ func (q QuoteModel) GetAll(content string, author string, filters Filters) ([]*Q
 	totalRecords := 0
 
 	quotes := []*Quote{}
+	// the sort values of the last quote, for the next cursor
+	var lastValues []string
+	hasMore := false
 
 	for rows.Next() {
 		var quote Quote
-		err := rows.Scan(
+		var submitterID sql.NullInt64
+		var submitterName sql.NullString
+		values := make([]string, len(filters.sortKeys()))
+
+		dest := []any{
 			&totalRecords,
 			&quote.ID,
 			&quote.CreatedAt,
+			&quote.UpdatedAt,
+			&quote.Views,
 			&quote.Content,
 			&quote.Author,
+			&quote.AuthorID,
+			pq.Array(&quote.Tags),
 			&quote.Version,
-		)
+			&quote.Status,
+			&quote.ModerationReason,
+			&submitterID,
+			&submitterName,
+		}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+
+		err := rows.Scan(dest...)
 		if err != nil {
 			return nil, Metadata{}, err
 		}
+		quote.SubmittedBy = newSubmitter(submitterID, submitterName)
+
+		// the extra record is only there to show there is more
+		if len(quotes) == filters.limit() {
+			hasMore = true
+			break
+		}
 
 		quotes = append(quotes, &quote)
+		lastValues = values
 	}
 
 	// check for errors from iterating over rows
@@ -195,7 +560,115 @@ func (q QuoteModel) GetAll(content string, author string, filters Filters) ([]*Q
 		return nil, Metadata{}, err
 	}
 
-	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	// with a cursor the count only covers what is left, so there are no
+	// page numbers to work out
+	var metadata Metadata
+	if filters.Cursor == "" {
+		metadata = calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	} else if len(quotes) > 0 {
+		metadata = Metadata{PageSize: filters.PageSize}
+	}
+
+	if hasMore {
+		metadata.NextCursor = filters.encodeCursor(lastValues)
+	}
 
 	return quotes, metadata, nil
 }
+
+// Get up to `count` different random approved quotes matching the content
+// and author filters. Instead of sorting the whole table with ORDER BY random() we pick
+// a random id and take the first matching quote at or after it, wrapping
+// around to the start of the table, so each probe is a short index scan.
+func (q QuoteModel) GetRandom(content string, author string, count int) ([]*Quote, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var minID, maxID sql.NullInt64
+	err := q.DB.QueryRowContext(ctx, `SELECT MIN(id), MAX(id) FROM quotes WHERE status = 'approved' AND deleted_at IS NULL`).Scan(&minID, &maxID)
+	if err != nil {
+		return nil, err
+	}
+
+	quotes := []*Quote{}
+
+	// an empty table
+	if !minID.Valid {
+		return quotes, nil
+	}
+
+	seen := make(map[int64]bool)
+
+	// when only a few quotes match, probes keep landing on the same ones,
+	// so give up after a few tries rather than looping forever
+	for attempts := 0; len(quotes) < count && attempts < count*3; attempts++ {
+		start := minID.Int64 + rand.Int64N(maxID.Int64-minID.Int64+1)
+
+		quote, err := q.firstMatchFrom(ctx, content, author, start)
+		if errors.Is(err, ErrRecordNotFound) {
+			// nothing at all matches the filters
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if !seen[quote.ID] {
+			seen[quote.ID] = true
+			quotes = append(quotes, quote)
+		}
+	}
+
+	return quotes, nil
+}
+
+// Get the first approved quote matching the filters with an id at or after start,
+// wrapping around to the lowest id if there is none
+func (q QuoteModel) firstMatchFrom(ctx context.Context, content string, author string, start int64) (*Quote, error) {
+	query := `
+        SELECT q.id, q.content, q.author, q.author_id, q.tags, q.created_at, q.updated_at, q.views, q.version, q.status, q.moderation_reason, u.id, u.username
+        FROM quotes q
+        LEFT JOIN users u ON u.id = q.user_id
+        WHERE (to_tsvector('simple', q.content) @@
+              plainto_tsquery('simple', $1) OR $1 = '')
+        AND (to_tsvector('simple', q.author) @@
+             plainto_tsquery('simple', $2) OR $2 = '')
+        AND q.id %s $3
+        AND q.status = 'approved'
+        AND q.deleted_at IS NULL
+        ORDER BY q.id ASC
+        LIMIT 1`
+
+	for _, comparison := range []string{">=", "<"} {
+		var quote Quote
+		var submitterID sql.NullInt64
+		var submitterName sql.NullString
+
+		err := q.DB.QueryRowContext(ctx, fmt.Sprintf(query, comparison), content, author, start).Scan(
+			&quote.ID,
+			&quote.Content,
+			&quote.Author,
+			&quote.AuthorID,
+			pq.Array(&quote.Tags),
+			&quote.CreatedAt,
+			&quote.UpdatedAt,
+			&quote.Views,
+			&quote.Version,
+			&quote.Status,
+			&quote.ModerationReason,
+			&submitterID,
+			&submitterName,
+		)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		quote.SubmittedBy = newSubmitter(submitterID, submitterName)
+		return &quote, nil
+	}
+
+	return nil, ErrRecordNotFound
+}
@@ -0,0 +1,228 @@
+// Filename: internal/data/revisions.go
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// What a quote looked like at one version
+type QuoteRevision struct {
+	QuoteID   int64      `json:"quote_id"`
+	Version   int32      `json:"version"`
+	Content   string     `json:"content"`
+	Author    string     `json:"author"`
+	AuthorID  int64      `json:"author_id"`
+	Tags      []string   `json:"tags"`
+	EditedBy  *Submitter `json:"edited_by"` // the user who made this version, if known
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// How one field changed between two revisions. Tags are lists so instead
+// of from and to they get the tags that were added and removed
+type FieldChange struct {
+	From    any      `json:"from,omitempty"`
+	To      any      `json:"to,omitempty"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// The fields that are different between two revisions
+type RevisionDiff struct {
+	FromVersion int32                  `json:"from_version"`
+	ToVersion   int32                  `json:"to_version"`
+	Changes     map[string]FieldChange `json:"changes"`
+}
+
+// Compare two revisions field by field
+func DiffRevisions(from *QuoteRevision, to *QuoteRevision) RevisionDiff {
+	diff := RevisionDiff{
+		FromVersion: from.Version,
+		ToVersion:   to.Version,
+		Changes:     make(map[string]FieldChange),
+	}
+
+	if from.Content != to.Content {
+		diff.Changes["content"] = FieldChange{From: from.Content, To: to.Content}
+	}
+	if from.Author != to.Author {
+		diff.Changes["author"] = FieldChange{From: from.Author, To: to.Author}
+	}
+	if from.AuthorID != to.AuthorID {
+		diff.Changes["author_id"] = FieldChange{From: from.AuthorID, To: to.AuthorID}
+	}
+
+	var added, removed []string
+	for _, tag := range to.Tags {
+		if !slices.Contains(from.Tags, tag) {
+			added = append(added, tag)
+		}
+	}
+	for _, tag := range from.Tags {
+		if !slices.Contains(to.Tags, tag) {
+			removed = append(removed, tag)
+		}
+	}
+	if len(added) > 0 || len(removed) > 0 {
+		diff.Changes["tags"] = FieldChange{Added: added, Removed: removed}
+	}
+
+	return diff
+}
+
+// Save the quote as it is now as a revision, unless that version is saved
+// already. userID is the user who made the version, if known
+func writeRevision(ctx context.Context, tx *sql.Tx, quoteID int64, userID *int64) error {
+	query := `
+		INSERT INTO quote_revisions (quote_id, version, content, author, author_id, tags, user_id, created_at)
+		SELECT id, version, content, author, author_id, tags, $2, updated_at
+		FROM quotes
+		WHERE id = $1
+		ON CONFLICT DO NOTHING
+		`
+
+	_, err := tx.ExecContext(ctx, query, quoteID, userID)
+	return err
+}
+
+// The RevisionModel expects a connection pool
+type RevisionModel struct {
+	DB *sql.DB
+}
+
+// Get one version of a quote
+func (m RevisionModel) Get(quoteID int64, version int32) (*QuoteRevision, error) {
+	query := `
+		SELECT r.quote_id, r.version, r.content, r.author, r.author_id, r.tags, r.created_at, u.id, u.username
+		FROM quote_revisions r
+		LEFT JOIN users u ON u.id = r.user_id
+		WHERE r.quote_id = $1 AND r.version = $2
+		`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	revision, err := scanRevision(m.DB.QueryRowContext(ctx, query, quoteID, version))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return revision, nil
+}
+
+// Get the saved version before the given one, for diffing against
+func (m RevisionModel) GetPrevious(quoteID int64, version int32) (*QuoteRevision, error) {
+	query := `
+		SELECT r.quote_id, r.version, r.content, r.author, r.author_id, r.tags, r.created_at, u.id, u.username
+		FROM quote_revisions r
+		LEFT JOIN users u ON u.id = r.user_id
+		WHERE r.quote_id = $1 AND r.version < $2
+		ORDER BY r.version DESC
+		LIMIT 1
+		`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	revision, err := scanRevision(m.DB.QueryRowContext(ctx, query, quoteID, version))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return revision, nil
+}
+
+// Get the saved versions of a quote, newest first
+func (m RevisionModel) GetAllForQuote(quoteID int64, filters Filters) ([]*QuoteRevision, Metadata, error) {
+	query := `
+		SELECT COUNT(*) OVER(), r.quote_id, r.version, r.content, r.author, r.author_id, r.tags, r.created_at, u.id, u.username
+		FROM quote_revisions r
+		LEFT JOIN users u ON u.id = r.user_id
+		WHERE r.quote_id = $1
+		ORDER BY r.version DESC
+		LIMIT $2 OFFSET $3
+		`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, quoteID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+	totalRecords := 0
+
+	revisions := []*QuoteRevision{}
+
+	for rows.Next() {
+		var revision QuoteRevision
+		var editorID sql.NullInt64
+		var editorName sql.NullString
+		err := rows.Scan(
+			&totalRecords,
+			&revision.QuoteID,
+			&revision.Version,
+			&revision.Content,
+			&revision.Author,
+			&revision.AuthorID,
+			pq.Array(&revision.Tags),
+			&revision.CreatedAt,
+			&editorID,
+			&editorName,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		revision.EditedBy = newSubmitter(editorID, editorName)
+		revisions = append(revisions, &revision)
+	}
+
+	// check for errors from iterating over rows
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return revisions, metadata, nil
+}
+
+// Read a single revision row
+func scanRevision(row *sql.Row) (*QuoteRevision, error) {
+	var revision QuoteRevision
+	var editorID sql.NullInt64
+	var editorName sql.NullString
+
+	err := row.Scan(
+		&revision.QuoteID,
+		&revision.Version,
+		&revision.Content,
+		&revision.Author,
+		&revision.AuthorID,
+		pq.Array(&revision.Tags),
+		&revision.CreatedAt,
+		&editorID,
+		&editorName,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	revision.EditedBy = newSubmitter(editorID, editorName)
+	return &revision, nil
+}
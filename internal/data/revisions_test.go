@@ -0,0 +1,31 @@
+// Filename: internal/data/revisions_test.go
+package data
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDiffRevisions(t *testing.T) {
+	from := &QuoteRevision{Version: 1, Content: "Stay hungry", Author: "Steve Jobs", AuthorID: 3, Tags: []string{"life", "work"}}
+	to := &QuoteRevision{Version: 2, Content: "Stay hungry, stay foolish", Author: "Steve Jobs", AuthorID: 3, Tags: []string{"work", "advice"}}
+
+	diff := DiffRevisions(from, to)
+	if diff.FromVersion != 1 || diff.ToVersion != 2 {
+		t.Errorf("expected versions 1 to 2, got %d to %d", diff.FromVersion, diff.ToVersion)
+	}
+	if len(diff.Changes) != 2 {
+		t.Fatalf("expected content and tags to change, got %v", diff.Changes)
+	}
+	if got := diff.Changes["content"]; got.From != from.Content || got.To != to.Content {
+		t.Errorf("unexpected content change %v", got)
+	}
+	tags := diff.Changes["tags"]
+	if !slices.Equal(tags.Added, []string{"advice"}) || !slices.Equal(tags.Removed, []string{"life"}) {
+		t.Errorf("unexpected tags change %v", tags)
+	}
+
+	if changes := DiffRevisions(from, from).Changes; len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", changes)
+	}
+}
@@ -0,0 +1,207 @@
+// Filename: internal/data/search.go
+package data
+
+import (
+	"context"
+	"database/sql"
+	"html"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/aiycoleman/qod/internal/validator"
+	"github.com/lib/pq"
+)
+
+// A quote found by a search, with how well it matched and the matching
+// words wrapped in <mark></mark>. The rest of the highlighted text is HTML
+// escaped, so the highlights are safe to show as HTML
+type SearchResult struct {
+	Quote      *Quote     `json:"quote"`
+	Rank       float32    `json:"rank"`
+	Highlights Highlights `json:"highlights"`
+}
+
+// Snippets of the quote fields with the matches highlighted
+type Highlights struct {
+	Content string `json:"content"`
+	Author  string `json:"author"`
+}
+
+// Check the search text
+func ValidateSearch(v *validator.Validator, search string) {
+	v.Check(search != "", "q", validator.NewError("required"))
+	v.Check(len(search) <= 200, "q", validator.NewError("max_bytes", "max", 200))
+	v.Check(search == "" || ToTSQuery(search) != "", "q", validator.NewError("no_words"))
+}
+
+// Turn what the user typed into a to_tsquery expression. Anything that is
+// not a letter or digit is dropped so the result is always valid syntax.
+//
+//	einstein imagination -> einstein & imagination
+//	"stay hungry"        -> (stay <-> hungry)
+//	imag*                -> imag:*
+//	-war                 -> !war
+func ToTSQuery(search string) string {
+	var terms []string
+
+	for _, token := range splitSearch(search) {
+		negate := false
+		if !token.phrase && strings.HasPrefix(token.text, "-") {
+			negate = true
+			token.text = token.text[1:]
+		}
+
+		prefix := !token.phrase && strings.HasSuffix(token.text, "*")
+
+		// the parser splits "don't" into "don" and "t", so we do too and
+		// keep the parts next to each other
+		words := strings.FieldsFunc(strings.ToLower(token.text), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		if len(words) == 0 {
+			continue
+		}
+		if prefix {
+			words[len(words)-1] += ":*"
+		}
+
+		term := strings.Join(words, " <-> ")
+		if len(words) > 1 {
+			term = "(" + term + ")"
+		}
+		if negate {
+			term = "!" + term
+		}
+		terms = append(terms, term)
+	}
+
+	return strings.Join(terms, " & ")
+}
+
+// A word, or a "quoted phrase", from the search text
+type searchToken struct {
+	text   string
+	phrase bool
+}
+
+// Split the search text on spaces, keeping "quoted phrases" together
+func splitSearch(search string) []searchToken {
+	var tokens []searchToken
+
+	for search != "" {
+		search = strings.TrimLeftFunc(search, unicode.IsSpace)
+		if search == "" {
+			break
+		}
+
+		if search[0] == '"' {
+			// an unclosed quote runs to the end
+			end := strings.IndexByte(search[1:], '"')
+			if end == -1 {
+				tokens = append(tokens, searchToken{text: search[1:], phrase: true})
+				break
+			}
+			tokens = append(tokens, searchToken{text: search[1 : end+1], phrase: true})
+			search = search[end+2:]
+			continue
+		}
+
+		end := strings.IndexFunc(search, unicode.IsSpace)
+		if end == -1 {
+			end = len(search)
+		}
+		tokens = append(tokens, searchToken{text: search[:end]})
+		search = search[end:]
+	}
+
+	return tokens
+}
+
+// ts_headline marks the matches with control characters instead of <mark>,
+// which can't be in a quote, so the text can be escaped before the real
+// tags go in
+const (
+	headlineStart   = "\x01"
+	headlineStop    = "\x02"
+	headlineOptions = "StartSel=" + headlineStart + ", StopSel=" + headlineStop + ", HighlightAll=true"
+)
+
+// HTML escape a headline and turn its match markers into <mark></mark>
+func highlight(headline string) string {
+	headline = html.EscapeString(headline)
+	headline = strings.ReplaceAll(headline, headlineStart, "<mark>")
+	return strings.ReplaceAll(headline, headlineStop, "</mark>")
+}
+
+// Search the content and author of every approved quote, best matches first
+func (q QuoteModel) Search(search string, filters Filters) ([]*SearchResult, Metadata, error) {
+	query := `
+        SELECT COUNT(*) OVER(), q.id, q.created_at, q.updated_at, q.views, q.content, q.author, q.author_id, q.tags, q.version, q.status, q.moderation_reason, u.id, u.username,
+               ts_rank(q.search_vector, query) AS rank,
+               ts_headline('simple', q.content, query, $4),
+               ts_headline('simple', q.author, query, $4)
+        FROM quotes q
+        CROSS JOIN to_tsquery('simple', $1) AS query
+        LEFT JOIN users u ON u.id = q.user_id
+        WHERE q.search_vector @@ query AND q.status = 'approved' AND q.deleted_at IS NULL
+        ORDER BY rank DESC, q.id ASC
+        LIMIT $2 OFFSET $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := q.DB.QueryContext(ctx, query, ToTSQuery(search), filters.limit(), filters.offset(), headlineOptions)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+	totalRecords := 0
+
+	results := []*SearchResult{}
+
+	for rows.Next() {
+		var quote Quote
+		var result SearchResult
+		var submitterID sql.NullInt64
+		var submitterName sql.NullString
+		err := rows.Scan(
+			&totalRecords,
+			&quote.ID,
+			&quote.CreatedAt,
+			&quote.UpdatedAt,
+			&quote.Views,
+			&quote.Content,
+			&quote.Author,
+			&quote.AuthorID,
+			pq.Array(&quote.Tags),
+			&quote.Version,
+			&quote.Status,
+			&quote.ModerationReason,
+			&submitterID,
+			&submitterName,
+			&result.Rank,
+			&result.Highlights.Content,
+			&result.Highlights.Author,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		result.Highlights.Content = highlight(result.Highlights.Content)
+		result.Highlights.Author = highlight(result.Highlights.Author)
+
+		quote.SubmittedBy = newSubmitter(submitterID, submitterName)
+		result.Quote = &quote
+		results = append(results, &result)
+	}
+
+	// check for errors from iterating over rows
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return results, metadata, nil
+}
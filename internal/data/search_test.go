@@ -0,0 +1,49 @@
+// Filename: internal/data/search_test.go
+package data
+
+import (
+	"testing"
+)
+
+func TestToTSQuery(t *testing.T) {
+	tests := []struct {
+		search string
+		want   string
+	}{
+		{"einstein imagination", "einstein & imagination"},
+		{`"stay hungry" jobs`, "(stay <-> hungry) & jobs"},
+		{"imag*", "imag:*"},
+		{"-war peace", "!war & peace"},
+		{"don't", "(don <-> t)"},
+		{`"unclosed phrase`, "(unclosed <-> phrase)"},
+		{"Ñandú", "ñandú"},
+		{"& | ! ( ) :*", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		got := ToTSQuery(tt.search)
+		if got != tt.want {
+			t.Errorf("ToTSQuery(%q): expected: %q, got: %q", tt.search, tt.want, got)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		headline string
+		want     string
+	}{
+		{"stay \x01hungry\x02", "stay <mark>hungry</mark>"},
+		{"<script>\x01alert\x02</script>", "&lt;script&gt;<mark>alert</mark>&lt;/script&gt;"},
+		{"Tom & \x01Jerry\x02's", "Tom &amp; <mark>Jerry</mark>&#39;s"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		got := highlight(tt.headline)
+		if got != tt.want {
+			t.Errorf("highlight(%q): expected: %q, got: %q", tt.headline, tt.want, got)
+		}
+	}
+}
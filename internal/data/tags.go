@@ -0,0 +1,99 @@
+// Filename: internal/data/tags.go
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// A tag and how many quotes use it
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// The TagModel expects a connection pool
+type TagModel struct {
+	DB *sql.DB
+}
+
+// Get every tag in use with the number of quotes using it, most used first
+func (t TagModel) GetAll() ([]*TagCount, error) {
+	query := `
+		SELECT tag, COUNT(*)
+		FROM quotes, unnest(quotes.tags) AS tag
+		WHERE quotes.status = 'approved' AND quotes.deleted_at IS NULL
+		GROUP BY tag
+		ORDER BY COUNT(*) DESC, tag ASC
+		`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := t.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []*TagCount{}
+
+	for rows.Next() {
+		var tag TagCount
+		err := rows.Scan(&tag.Tag, &tag.Count)
+		if err != nil {
+			return nil, err
+		}
+
+		tags = append(tags, &tag)
+	}
+
+	// check for errors from iterating over rows
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// Get the number of quotes using each of the given tags, keyed by tag
+func (t TagModel) GetCounts(tags []string) (map[string]int, error) {
+	query := `
+		SELECT tag, COUNT(*)
+		FROM quotes, unnest(quotes.tags) AS tag
+		WHERE tag = ANY($1) AND quotes.status = 'approved' AND quotes.deleted_at IS NULL
+		GROUP BY tag
+		`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := t.DB.QueryContext(ctx, query, pq.Array(tags))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int, len(tags))
+
+	for rows.Next() {
+		var tag string
+		var count int
+		err := rows.Scan(&tag, &count)
+		if err != nil {
+			return nil, err
+		}
+
+		counts[tag] = count
+	}
+
+	// check for errors from iterating over rows
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
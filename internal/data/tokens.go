@@ -0,0 +1,91 @@
+// Filename: internal/data/tokens.go
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"time"
+
+	"github.com/aiycoleman/qod/internal/validator"
+)
+
+// The different things a token can be used for
+const (
+	ScopeActivation     = "activation"
+	ScopeAuthentication = "authentication"
+)
+
+// A token hands out the plaintext once, we only ever store the hash
+type Token struct {
+	Plaintext string    `json:"token"`
+	Hash      []byte    `json:"-"`
+	UserID    int64     `json:"-"`
+	Expiry    time.Time `json:"expiry"`
+	Scope     string    `json:"-"`
+}
+
+// Create a token for a user that expires after the time-to-live
+func generateToken(userID int64, ttl time.Duration, scope string) *Token {
+	token := &Token{
+		// 26 random base-32 characters (128 bits of randomness)
+		Plaintext: rand.Text(),
+		UserID:    userID,
+		Expiry:    time.Now().Add(ttl),
+		Scope:     scope,
+	}
+
+	hash := sha256.Sum256([]byte(token.Plaintext))
+	token.Hash = hash[:]
+
+	return token
+}
+
+// Check that the token the client sent looks like one of ours
+func ValidateTokenPlaintext(v *validator.Validator, tokenPlaintext string) {
+	v.Check(tokenPlaintext != "", "token", validator.NewError("required"))
+	v.Check(len(tokenPlaintext) == 26, "token", validator.NewError("exact_bytes", "length", 26))
+}
+
+// The TokenModel expects a connection pool
+type TokenModel struct {
+	DB *sql.DB
+}
+
+// Generate a new token and save it in the db
+func (t TokenModel) New(userID int64, ttl time.Duration, scope string) (*Token, error) {
+	token := generateToken(userID, ttl, scope)
+
+	err := t.Insert(token)
+	return token, err
+}
+
+// Insert a token into the tokens table
+func (t TokenModel) Insert(token *Token) error {
+	query := `
+		INSERT INTO tokens (hash, user_id, expiry, scope)
+		VALUES ($1, $2, $3, $4)
+		`
+	args := []any{token.Hash, token.UserID, token.Expiry, token.Scope}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := t.DB.ExecContext(ctx, query, args...)
+	return err
+}
+
+// Delete every token with the given scope for a user
+func (t TokenModel) DeleteAllForUser(scope string, userID int64) error {
+	query := `
+		DELETE FROM tokens
+		WHERE scope = $1 AND user_id = $2
+		`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := t.DB.ExecContext(ctx, query, scope, userID)
+	return err
+}
@@ -0,0 +1,173 @@
+// Filename: internal/data/trash.go
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Get a quote that is in the trash
+func (q QuoteModel) GetDeleted(id int64) (*Quote, error) {
+	// check if the id is valid
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT q.id, q.content, q.author, q.author_id, q.tags, q.created_at, q.updated_at, q.views, q.version, q.status, q.moderation_reason, q.deleted_at, u.id, u.username
+		FROM quotes q
+		LEFT JOIN users u ON u.id = q.user_id
+		WHERE q.id = $1 AND q.deleted_at IS NOT NULL
+		`
+	var quote Quote
+	var submitterID sql.NullInt64
+	var submitterName sql.NullString
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := q.DB.QueryRowContext(ctx, query, id).Scan(
+		&quote.ID,
+		&quote.Content,
+		&quote.Author,
+		&quote.AuthorID,
+		pq.Array(&quote.Tags),
+		&quote.CreatedAt,
+		&quote.UpdatedAt,
+		&quote.Views,
+		&quote.Version,
+		&quote.Status,
+		&quote.ModerationReason,
+		&quote.DeletedAt,
+		&submitterID,
+		&submitterName,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	quote.SubmittedBy = newSubmitter(submitterID, submitterName)
+
+	return &quote, nil
+}
+
+// Take a quote out of the trash, as long as it is still at the version we
+// read. If the same quote was added again while this one was in the trash
+// ErrDuplicateQuote is returned
+func (q QuoteModel) Restore(quote *Quote) error {
+	query := `
+        UPDATE quotes
+        SET deleted_at = NULL, version = version + 1
+        WHERE id = $1 AND version = $2 AND deleted_at IS NOT NULL
+        RETURNING version
+		`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := q.DB.QueryRowContext(ctx, query, quote.ID, quote.Version).Scan(&quote.Version)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err, "quotes_fingerprint_idx"):
+			return ErrDuplicateQuote
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	quote.DeletedAt = nil
+	return nil
+}
+
+// Get the quotes in the trash, most recently deleted first. A userID of 0
+// means the trash of every user
+func (q QuoteModel) GetTrash(userID int64, filters Filters) ([]*Quote, Metadata, error) {
+	query := `
+        SELECT COUNT(*) OVER(), q.id, q.content, q.author, q.author_id, q.tags, q.created_at, q.updated_at, q.views, q.version, q.status, q.moderation_reason, q.deleted_at, u.id, u.username
+        FROM quotes q
+        LEFT JOIN users u ON u.id = q.user_id
+        WHERE q.deleted_at IS NOT NULL
+        AND (q.user_id = $1 OR $1 = 0)
+        ORDER BY q.deleted_at DESC, q.id DESC
+        LIMIT $2 OFFSET $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := q.DB.QueryContext(ctx, query, userID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+	totalRecords := 0
+
+	quotes := []*Quote{}
+
+	for rows.Next() {
+		var quote Quote
+		var submitterID sql.NullInt64
+		var submitterName sql.NullString
+		err := rows.Scan(
+			&totalRecords,
+			&quote.ID,
+			&quote.Content,
+			&quote.Author,
+			&quote.AuthorID,
+			pq.Array(&quote.Tags),
+			&quote.CreatedAt,
+			&quote.UpdatedAt,
+			&quote.Views,
+			&quote.Version,
+			&quote.Status,
+			&quote.ModerationReason,
+			&quote.DeletedAt,
+			&submitterID,
+			&submitterName,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		quote.SubmittedBy = newSubmitter(submitterID, submitterName)
+		quotes = append(quotes, &quote)
+	}
+
+	// check for errors from iterating over rows
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return quotes, metadata, nil
+}
+
+// Permanently remove the quotes that have been in the trash for longer than
+// the retention period, returning how many were removed
+func (q QuoteModel) Purge(retention time.Duration) (int64, error) {
+	query := `
+        DELETE FROM quotes
+        WHERE deleted_at < $1
+		`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result, err := q.DB.ExecContext(ctx, query, time.Now().Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
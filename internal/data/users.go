@@ -3,6 +3,7 @@ package data
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
 	"errors"
 	"time"
@@ -24,6 +25,14 @@ type User struct {
 	Version   int       `json:"-"`
 }
 
+// A user who has not authenticated
+var AnonymousUser = &User{}
+
+// Check if a user is the anonymous user
+func (u *User) IsAnonymous() bool {
+	return u == AnonymousUser
+}
+
 type password struct {
 	plaintext *string
 	hash      []byte
@@ -61,21 +70,20 @@ func (p *password) Matches(plaintextPassword string) (bool, error) {
 
 // Validate the email address
 func ValidateEmail(v *validator.Validator, email string) {
-	v.Check(email != "", "email", "must be provided")
-	v.Check(validator.Matches(email, validator.EmailRX), "email", "must be a valid email address")
+	v.Check(email != "", "email", validator.NewError("required"))
+	v.Check(validator.Matches(email, validator.EmailRX), "email", validator.NewError("invalid_email"))
 }
 
 // Check that a valid password is provided
 func ValidatePasswordPlaintext(v *validator.Validator, password string) {
-	v.Check(password != "", "password", "must be provided")
-	v.Check(len(password) >= 8, "password", "must be at least 8 bytes long")
-	v.Check(len(password) <= 72, "password", "must not be more than 72 bytes long")
+	v.Check(password != "", "password", validator.NewError("required"))
+	v.Check(len(password) >= 8, "password", validator.NewError("min_bytes", "min", 8))
+	v.Check(len(password) <= 72, "password", validator.NewError("max_bytes", "max", 72))
 }
 
 // Validate a user
-func ValidateUser(v *validator.Validator, user User) {
-	v.Check(user.Username != "", "username", "must be provided")
-	v.Check(len(user.Username) <= 200, "username", "must not be more than 200 bytes long")
+func ValidateUser(v *validator.Validator, user User, limits TextLimits) {
+	validateText(v, "username", user.Username, limits.Username)
 
 	// validate email for user
 	ValidateEmail(v, user.Email)
@@ -117,6 +125,45 @@ func (u UserModel) Insert(user *User) error {
 	return nil
 }
 
+// Get a specific user from the db
+func (u UserModel) Get(id int64) (*User, error) {
+	// check if the id is valid
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+			SELECT id, created_at, username, email, password_hash, activated, version
+			FROM users
+			WHERE id = $1
+			`
+
+	var user User
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := u.DB.QueryRowContext(ctx, query, id).Scan(
+		&user.ID,
+		&user.CreatedAt,
+		&user.Username,
+		&user.Email,
+		&user.Password.hash,
+		&user.Activated,
+		&user.Version,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &user, nil
+}
+
 // Get a user from the db based on their emial provided
 func (u UserModel) GetByEmail(email string) (*User, error) {
 	query := `
@@ -187,3 +234,46 @@ func (u UserModel) Update(user *User) error {
 	}
 	return nil
 }
+
+// Get the user that owns a token with the given scope, as long as the
+// token has not expired
+func (u UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error) {
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	query := `
+		SELECT users.id, users.created_at, users.username, users.email,
+		       users.password_hash, users.activated, users.version
+		FROM users
+		INNER JOIN tokens
+		ON users.id = tokens.user_id
+		WHERE tokens.hash = $1
+		AND tokens.scope = $2
+		AND tokens.expiry > $3
+		`
+	args := []any{tokenHash[:], tokenScope, time.Now()}
+
+	var user User
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := u.DB.QueryRowContext(ctx, query, args...).Scan(
+		&user.ID,
+		&user.CreatedAt,
+		&user.Username,
+		&user.Email,
+		&user.Password.hash,
+		&user.Activated,
+		&user.Version,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &user, nil
+}
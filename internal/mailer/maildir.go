@@ -0,0 +1,52 @@
+// Filename: internal/mailer/maildir.go
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// Drop messages into a local maildir instead of sending them. Handy in
+// development and tests, any mail client that reads maildirs can open it.
+type MaildirSender struct {
+	dir   string
+	count atomic.Int64 // keeps file names unique within this process
+}
+
+// Construct a new MaildirSender, creating the tmp/new/cur folders if needed
+func NewMaildirSender(dir string) (*MaildirSender, error) {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		err := os.MkdirAll(filepath.Join(dir, sub), 0o755)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &MaildirSender{dir: dir}, nil
+}
+
+// Write the message into tmp/ and then move it into new/ so a reader never
+// sees half a message
+func (s *MaildirSender) Send(msg Message) error {
+	body, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+	name := fmt.Sprintf("%d.%d_%d.%s", time.Now().Unix(), os.Getpid(), s.count.Add(1), hostname)
+
+	tmpPath := filepath.Join(s.dir, "tmp", name)
+	err = os.WriteFile(tmpPath, body, 0o644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, filepath.Join(s.dir, "new", name))
+}
@@ -0,0 +1,161 @@
+// Filename: internal/mailer/mailer.go
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// The email templates are compiled into the binary
+//
+//go:embed "templates"
+var templateFS embed.FS
+
+// A rendered email ready to be handed to a Sender
+type Message struct {
+	From      string
+	To        string
+	Subject   string
+	PlainBody string
+	HTMLBody  string
+}
+
+// A Sender delivers a message somewhere (an SMTP server, a directory...)
+type Sender interface {
+	Send(msg Message) error
+}
+
+// The Mailer renders templates into messages and passes them to its Sender
+type Mailer struct {
+	sender Sender
+	from   string // e.g. "Quote of the Day <no-reply@qod.example.com>"
+}
+
+// Construct a new Mailer that sends from the given address
+func New(sender Sender, from string) *Mailer {
+	return &Mailer{
+		sender: sender,
+		from:   from,
+	}
+}
+
+// Render the named template with the data and send it to the recipient.
+// The template must define "subject", "plainBody" and "htmlBody".
+func (m *Mailer) Send(recipient string, templateFile string, data any) error {
+	msg, err := m.render(recipient, templateFile, data)
+	if err != nil {
+		return err
+	}
+
+	// try a few times in case the server has a temporary problem
+	for i := 1; i <= 3; i++ {
+		err = m.sender.Send(msg)
+		if err == nil {
+			return nil
+		}
+
+		// don't wait after the last attempt
+		if i != 3 {
+			time.Sleep(500 * time.Millisecond)
+		}
+	}
+
+	return err
+}
+
+// Build the message from the template
+func (m *Mailer) render(recipient string, templateFile string, data any) (Message, error) {
+	msg := Message{
+		From: m.from,
+		To:   recipient,
+	}
+
+	// the subject and plain body are text, so no HTML escaping
+	textTmpl, err := template.New("email").ParseFS(templateFS, "templates/"+templateFile)
+	if err != nil {
+		return Message{}, err
+	}
+
+	subject := new(bytes.Buffer)
+	err = textTmpl.ExecuteTemplate(subject, "subject", data)
+	if err != nil {
+		return Message{}, err
+	}
+	msg.Subject = strings.TrimSpace(subject.String())
+
+	plainBody := new(bytes.Buffer)
+	err = textTmpl.ExecuteTemplate(plainBody, "plainBody", data)
+	if err != nil {
+		return Message{}, err
+	}
+	msg.PlainBody = plainBody.String()
+
+	// the HTML body escapes whatever data is put into it
+	htmlTmpl, err := htmltemplate.New("email").ParseFS(templateFS, "templates/"+templateFile)
+	if err != nil {
+		return Message{}, err
+	}
+
+	htmlBody := new(bytes.Buffer)
+	err = htmlTmpl.ExecuteTemplate(htmlBody, "htmlBody", data)
+	if err != nil {
+		return Message{}, err
+	}
+	msg.HTMLBody = htmlBody.String()
+
+	return msg, nil
+}
+
+// Bytes formats the message as a MIME email with a plain text part and an
+// HTML part, so mail clients can pick whichever they prefer
+func (msg Message) Bytes() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	writer := multipart.NewWriter(buf)
+
+	headers := []string{
+		"From: " + msg.From,
+		"To: " + msg.To,
+		"Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		fmt.Sprintf("Content-Type: multipart/alternative; boundary=%q", writer.Boundary()),
+	}
+	buf.WriteString(strings.Join(headers, "\r\n") + "\r\n\r\n")
+
+	parts := []struct {
+		contentType string
+		body        string
+	}{
+		{"text/plain; charset=utf-8", msg.PlainBody},
+		{"text/html; charset=utf-8", msg.HTMLBody},
+	}
+
+	for _, part := range parts {
+		w, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"8bit"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		_, err = w.Write([]byte(part.body))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := writer.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,55 @@
+// Filename: internal/mailer/mailer_test.go
+package mailer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMaildirSend(t *testing.T) {
+	dir := t.TempDir()
+
+	sender, err := NewMaildirSender(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := New(sender, "QOD <no-reply@qod.example.com>")
+	data := map[string]any{
+		"activationToken": "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+		"userID":          7,
+		"username":        "<b>ub</b>",
+	}
+
+	err = m.Send("ub@example.com", "user_welcome.tmpl", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := os.ReadDir(filepath.Join(dir, "new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected: 1 message, got: %d", len(files))
+	}
+
+	body, err := os.ReadFile(filepath.Join(dir, "new", files[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"To: ub@example.com",
+		"Subject: Welcome to Quote of the Day!",
+		"ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+		"Hi <b>ub</b>,",             // plain text is not escaped
+		"Hi &lt;b&gt;ub&lt;/b&gt;,", // HTML is
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("expected message to contain %q", want)
+		}
+	}
+}
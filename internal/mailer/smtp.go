@@ -0,0 +1,53 @@
+// Filename: internal/mailer/smtp.go
+package mailer
+
+import (
+	"fmt"
+	"net/mail"
+	"net/smtp"
+)
+
+// Deliver messages through an SMTP server
+type SMTPSender struct {
+	host     string
+	port     int
+	username string
+	password string
+}
+
+// Construct a new SMTPSender
+func NewSMTPSender(host string, port int, username, password string) *SMTPSender {
+	return &SMTPSender{
+		host:     host,
+		port:     port,
+		username: username,
+		password: password,
+	}
+}
+
+// Send the message to the SMTP server
+func (s *SMTPSender) Send(msg Message) error {
+	// the envelope needs the bare addresses, not "Name <address>"
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return err
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return err
+	}
+
+	body, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	// only log in when we were given credentials (local relays often don't need them)
+	var auth smtp.Auth
+	if s.username != "" {
+		auth = smtp.PlainAuth("", s.username, s.password, s.host)
+	}
+
+	addr := fmt.Sprintf("%s:%d", s.host, s.port)
+	return smtp.SendMail(addr, auth, from.Address, []string{to.Address}, body)
+}
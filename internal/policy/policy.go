@@ -0,0 +1,138 @@
+// Filename: internal/policy/policy.go
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aiycoleman/qod/internal/validator"
+)
+
+// What happens to a quote that breaks a rule
+type Action string
+
+const (
+	Reject   Action = "reject"   // the quote is not accepted
+	Moderate Action = "moderate" // the quote waits for a moderator
+)
+
+// Parse an action from the configuration. "off" means the rule isn't used
+func ParseAction(s string) (action Action, enabled bool, err error) {
+	switch s {
+	case string(Reject), string(Moderate):
+		return Action(s), true, nil
+	case "off":
+		return "", false, nil
+	default:
+		return "", false, fmt.Errorf("unknown content policy action %q (reject|moderate|off)", s)
+	}
+}
+
+// A Rule checks a piece of text, e.g. for links or blocked words
+type Rule interface {
+	// a short name for the rule, e.g. "links"
+	Name() string
+	// why the text breaks the rule, as a coded error like the validator's,
+	// and whether it does at all
+	Check(text string) (validator.Error, bool)
+}
+
+// One rule broken by one field of a quote. The error's code, params and
+// message sit alongside the other fields in JSON
+type Violation struct {
+	Field  string `json:"field"`
+	Rule   string `json:"rule"`
+	Action Action `json:"action"`
+	validator.Error
+}
+
+type Violations []Violation
+
+// Check if any of the violations means the quote is not accepted
+func (vs Violations) Rejected() bool {
+	for _, v := range vs {
+		if v.Action == Reject {
+			return true
+		}
+	}
+	return false
+}
+
+// Check if any of the violations means the quote needs a moderator
+func (vs Violations) Moderated() bool {
+	for _, v := range vs {
+		if v.Action == Moderate {
+			return true
+		}
+	}
+	return false
+}
+
+// The violations that reject the quote, with their messages in a language
+func (vs Violations) RejectedIn(lang string) Violations {
+	var rejected Violations
+	for _, v := range vs {
+		if v.Action == Reject {
+			v.Error = v.Error.Localize(lang)
+			rejected = append(rejected, v)
+		}
+	}
+	return rejected
+}
+
+// Add the rejecting violations to a validator, so they are reported (and
+// translated) like any other validation error
+func (vs Violations) AddErrors(v *validator.Validator) {
+	for _, violation := range vs {
+		if violation.Action == Reject {
+			v.AddError(violation.Field, violation.Error)
+		}
+	}
+}
+
+// A one line summary for a moderator, e.g. "content: must not contain links".
+// It is saved with the quote so it is always in the default language
+func (vs Violations) Summary() string {
+	reasons := make([]string, 0, len(vs))
+	for _, v := range vs {
+		reasons = append(reasons, v.Field+": "+v.Message)
+	}
+	return strings.Join(reasons, "; ")
+}
+
+type policyRule struct {
+	rule   Rule
+	action Action
+}
+
+// A ContentPolicy runs every rule added to it over the text of a quote
+type ContentPolicy struct {
+	rules []policyRule
+}
+
+// Construct an empty policy, which allows everything
+func New() *ContentPolicy {
+	return &ContentPolicy{}
+}
+
+// Add a rule and what to do with quotes that break it
+func (p *ContentPolicy) Add(rule Rule, action Action) {
+	p.rules = append(p.rules, policyRule{rule: rule, action: action})
+}
+
+// Run every rule over the text of a field
+func (p *ContentPolicy) Check(field string, text string) Violations {
+	var violations Violations
+	for _, r := range p.rules {
+		e, broken := r.rule.Check(text)
+		if broken {
+			violations = append(violations, Violation{
+				Field:  field,
+				Rule:   r.rule.Name(),
+				Action: r.action,
+				Error:  e,
+			})
+		}
+	}
+	return violations
+}
@@ -0,0 +1,71 @@
+// Filename: internal/policy/policy_test.go
+package policy
+
+import (
+	"testing"
+
+	"github.com/aiycoleman/qod/internal/validator"
+)
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  Rule
+		text  string
+		broke bool
+	}{
+		{"blocked word", NewWordList([]string{"spam"}), "This is SPAM!", true},
+		{"blocked phrase", NewWordList([]string{"buy now"}), "Buy, now.", true},
+		{"word inside another word", NewWordList([]string{"ass"}), "Stay in class", false},
+		{"link", Links{}, "see https://example.com", true},
+		{"bare domain", Links{}, "visit example.com today", true},
+		{"no link", Links{}, "Stay hungry. Stay foolish.", false},
+		{"shouting", AllCaps{MinLetters: 10, Ratio: 0.8}, "STAY HUNGRY STAY FOOLISH", true},
+		{"acronym", AllCaps{MinLetters: 10, Ratio: 0.8}, "NASA", false},
+		{"mixed case", AllCaps{MinLetters: 10, Ratio: 0.8}, "Stay Hungry Stay Foolish", false},
+		{"repeated character", RepeatedCharacters{Max: 4}, "sooooo good", true},
+		{"ellipsis", RepeatedCharacters{Max: 4}, "well...", false},
+		{"repeated word", RepeatedWords{Max: 3}, "buy buy buy buy", true},
+		{"repeated word apart", RepeatedWords{Max: 3}, "buy it, buy it, buy it, buy it", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, got := tt.rule.Check(tt.text)
+			if got != tt.broke {
+				t.Errorf("%s on %q: expected broken %t, got %t", tt.rule.Name(), tt.text, tt.broke, got)
+			}
+		})
+	}
+}
+
+func TestContentPolicy(t *testing.T) {
+	p := New()
+	p.Add(NewWordList([]string{"spam"}), Reject)
+	p.Add(Links{}, Moderate)
+
+	violations := p.Check("content", "spam at example.com")
+	if len(violations) != 2 {
+		t.Fatalf("expected: 2 violations, got: %d", len(violations))
+	}
+	if !violations.Rejected() || !violations.Moderated() {
+		t.Error("expected the violations to reject and moderate")
+	}
+	v := validator.New()
+	violations.AddErrors(v)
+	if v.Errors["content"] != "must not contain blocked words" || len(v.FieldErrors["content"]) != 1 {
+		t.Errorf("unexpected errors %v", v.FieldErrors)
+	}
+	if rejected := violations.RejectedIn("es"); len(rejected) != 1 || rejected[0].Message != "no debe contener palabras bloqueadas" {
+		t.Errorf("unexpected rejected violations %v", rejected)
+	}
+
+	violations = p.Check("content", "see example.com")
+	if violations.Rejected() || !violations.Moderated() {
+		t.Error("expected a link to only need moderation")
+	}
+
+	if violations := p.Check("content", "Stay hungry"); len(violations) != 0 {
+		t.Errorf("expected no violations, got %v", violations)
+	}
+}
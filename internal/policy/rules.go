@@ -0,0 +1,147 @@
+// Filename: internal/policy/rules.go
+package policy
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/aiycoleman/qod/internal/validator"
+)
+
+// Split text into lowercase words, dropping punctuation
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+}
+
+// Blocks words and phrases from a list. Matching ignores case and
+// punctuation, and only whole words match so "class" doesn't hit "ass"
+type WordList struct {
+	phrases []string
+}
+
+func NewWordList(list []string) *WordList {
+	wl := &WordList{}
+	for _, entry := range list {
+		phrase := strings.Join(words(entry), " ")
+		if phrase != "" {
+			wl.phrases = append(wl.phrases, " "+phrase+" ")
+		}
+	}
+	return wl
+}
+
+func (wl *WordList) Name() string {
+	return "words"
+}
+
+func (wl *WordList) Check(text string) (validator.Error, bool) {
+	// spaces around every word so phrases only match whole words
+	normalized := " " + strings.Join(words(text), " ") + " "
+	for _, phrase := range wl.phrases {
+		if strings.Contains(normalized, phrase) {
+			return validator.NewError("blocked_words"), true
+		}
+	}
+	return validator.Error{}, false
+}
+
+// Links with a scheme or www., and bare domains with a common top-level
+// domain like example.com
+var linkRX = regexp.MustCompile(`(?i)(https?://|www\.)\S+|\b[a-z0-9-]+\.(com|net|org|io|co|info|biz|xyz|ru|ly|me)\b`)
+
+// Blocks links, which are the usual sign of spam
+type Links struct{}
+
+func (Links) Name() string {
+	return "links"
+}
+
+func (Links) Check(text string) (validator.Error, bool) {
+	if linkRX.MatchString(text) {
+		return validator.NewError("links"), true
+	}
+	return validator.Error{}, false
+}
+
+// Blocks text that is shouted. Text with fewer than MinLetters letters is
+// left alone so acronyms and short names are fine
+type AllCaps struct {
+	MinLetters int
+	Ratio      float64 // the share (0 to 1) of the letters that have to be capitals
+}
+
+func (ac AllCaps) Name() string {
+	return "all_caps"
+}
+
+func (ac AllCaps) Check(text string) (validator.Error, bool) {
+	upper, cased := 0, 0
+	for _, r := range text {
+		// letters without case (e.g. Chinese) don't count either way
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+			cased++
+		case unicode.IsLower(r):
+			cased++
+		}
+	}
+
+	if cased >= ac.MinLetters && float64(upper) >= ac.Ratio*float64(cased) {
+		return validator.NewError("all_caps"), true
+	}
+	return validator.Error{}, false
+}
+
+// Blocks the same character many times in a row, like "sooooo" or "!!!!!!"
+type RepeatedCharacters struct {
+	Max int
+}
+
+func (rc RepeatedCharacters) Name() string {
+	return "repeated_characters"
+}
+
+func (rc RepeatedCharacters) Check(text string) (validator.Error, bool) {
+	var previous rune
+	run := 0
+	for _, r := range text {
+		if r == previous {
+			run++
+		} else {
+			previous, run = r, 1
+		}
+		if run > rc.Max && !unicode.IsSpace(r) {
+			return validator.NewError("repeated_characters", "max", rc.Max), true
+		}
+	}
+	return validator.Error{}, false
+}
+
+// Blocks the same word many times in a row, like "buy buy buy buy"
+type RepeatedWords struct {
+	Max int
+}
+
+func (rw RepeatedWords) Name() string {
+	return "repeated_words"
+}
+
+func (rw RepeatedWords) Check(text string) (validator.Error, bool) {
+	var previous string
+	run := 0
+	for _, word := range words(text) {
+		if word == previous {
+			run++
+		} else {
+			previous, run = word, 1
+		}
+		if run > rw.Max {
+			return validator.NewError("repeated_words", "max", rw.Max), true
+		}
+	}
+	return validator.Error{}, false
+}
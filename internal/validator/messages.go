@@ -0,0 +1,133 @@
+// Filename: internal/validator/messages.go
+package validator
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// The language used when the client doesn't ask for one we have
+const DefaultLanguage = "en"
+
+// The message for each error code in each language. {name} is replaced by
+// the error's param of the same name
+var catalogue = map[string]map[string]string{
+	"en": {
+		"required":            "must be provided",
+		"empty":               "must not be empty",
+		"invalid_value":       "is not a permitted value",
+		"not_permitted":       "must be one of {values}",
+		"invalid_integer":     "must be an integer value",
+		"invalid_time":        "must be an RFC3339 timestamp or a YYYY-MM-DD date",
+		"invalid_email":       "must be a valid email address",
+		"invalid_cursor":      "must be a cursor returned for the same sort",
+		"invalid_tag":         "must only contain lowercase letters, digits and hyphens",
+		"invalid_token":       "invalid or expired activation token",
+		"positive":            "must be greater than zero",
+		"not_negative":        "must not be negative",
+		"max_value":           "must be a maximum of {max}",
+		"min_bytes":           "must be at least {min} bytes long",
+		"max_bytes":           "must not be more than {max} bytes long",
+		"exact_bytes":         "must be {length} bytes long",
+		"max_chars":           "must not be more than {max} characters long",
+		"max_items":           "must not contain more than {max} values",
+		"max_item_bytes":      "must not contain values more than {max} bytes long",
+		"max_item_chars":      "must not contain values more than {max} characters long",
+		"empty_values":        "must not contain empty values",
+		"duplicate_values":    "must not contain duplicate values",
+		"untidy_values":       "must not contain values with extra whitespace, control characters or text not in NFC form",
+		"not_nfc":             "must be valid UTF-8 in NFC form",
+		"untrimmed":           "must not start or end with whitespace",
+		"control_characters":  "must not contain control characters",
+		"no_words":            "must contain at least one word",
+		"later_than":          "must be later than {field}",
+		"conflicts_with":      "must not be used together with {field}",
+		"future_date":         "must be a future date",
+		"in_future":           "must not be in the future",
+		"before_birth":        "must not be before the birth year",
+		"unknown_quote":       "must reference an existing quote",
+		"unapproved_quote":    "must reference an approved quote",
+		"unknown_author":      "must reference an existing author",
+		"unknown_version":     "must be a saved version of the quote",
+		"not_earlier_version": "must be an earlier version of the quote",
+		"duplicate_email":     "a user with this email address already exists",
+		"duplicate_author":    "an author with this name already exists",
+		"required_to_reject":  "must be provided when rejecting a quote",
+		"blocked_words":       "must not contain blocked words",
+		"links":               "must not contain links",
+		"all_caps":            "must not be written in capitals",
+		"repeated_characters": "must not repeat a character more than {max} times in a row",
+		"repeated_words":      "must not repeat a word more than {max} times in a row",
+	},
+	"es": {
+		"required":            "es obligatorio",
+		"empty":               "no debe estar vacío",
+		"invalid_value":       "no es un valor permitido",
+		"not_permitted":       "debe ser uno de {values}",
+		"invalid_integer":     "debe ser un número entero",
+		"invalid_time":        "debe ser una marca de tiempo RFC3339 o una fecha AAAA-MM-DD",
+		"invalid_email":       "debe ser una dirección de correo electrónico válida",
+		"invalid_cursor":      "debe ser un cursor devuelto para el mismo orden",
+		"invalid_tag":         "solo debe contener letras minúsculas, dígitos y guiones",
+		"invalid_token":       "token de activación no válido o caducado",
+		"positive":            "debe ser mayor que cero",
+		"not_negative":        "no debe ser negativo",
+		"max_value":           "debe ser como máximo {max}",
+		"min_bytes":           "debe tener al menos {min} bytes",
+		"max_bytes":           "no debe tener más de {max} bytes",
+		"exact_bytes":         "debe tener {length} bytes",
+		"max_chars":           "no debe tener más de {max} caracteres",
+		"max_items":           "no debe contener más de {max} valores",
+		"max_item_bytes":      "no debe contener valores de más de {max} bytes",
+		"max_item_chars":      "no debe contener valores de más de {max} caracteres",
+		"empty_values":        "no debe contener valores vacíos",
+		"duplicate_values":    "no debe contener valores repetidos",
+		"untidy_values":       "no debe contener valores con espacios sobrantes, caracteres de control o texto que no esté en forma NFC",
+		"not_nfc":             "debe ser UTF-8 válido en forma NFC",
+		"untrimmed":           "no debe empezar ni terminar con espacios",
+		"control_characters":  "no debe contener caracteres de control",
+		"no_words":            "debe contener al menos una palabra",
+		"later_than":          "debe ser posterior a {field}",
+		"conflicts_with":      "no debe usarse junto con {field}",
+		"future_date":         "debe ser una fecha futura",
+		"in_future":           "no debe estar en el futuro",
+		"before_birth":        "no debe ser anterior al año de nacimiento",
+		"unknown_quote":       "debe hacer referencia a una cita existente",
+		"unapproved_quote":    "debe hacer referencia a una cita aprobada",
+		"unknown_author":      "debe hacer referencia a un autor existente",
+		"unknown_version":     "debe ser una versión guardada de la cita",
+		"not_earlier_version": "debe ser una versión anterior de la cita",
+		"duplicate_email":     "ya existe un usuario con esta dirección de correo electrónico",
+		"duplicate_author":    "ya existe un autor con este nombre",
+		"required_to_reject":  "es obligatorio al rechazar una cita",
+		"blocked_words":       "no debe contener palabras bloqueadas",
+		"links":               "no debe contener enlaces",
+		"all_caps":            "no debe estar escrito en mayúsculas",
+		"repeated_characters": "no debe repetir un carácter más de {max} veces seguidas",
+		"repeated_words":      "no debe repetir una palabra más de {max} veces seguidas",
+	},
+}
+
+// The languages there are messages for
+func Languages() []string {
+	return slices.Sorted(maps.Keys(catalogue))
+}
+
+// Render the message for an error code in a language, falling back to
+// English and then to the code itself
+func Message(lang string, code string, params map[string]any) string {
+	template, ok := catalogue[lang][code]
+	if !ok {
+		template, ok = catalogue[DefaultLanguage][code]
+	}
+	if !ok {
+		return code
+	}
+
+	for name, value := range params {
+		template = strings.ReplaceAll(template, "{"+name+"}", fmt.Sprint(value))
+	}
+	return template
+}
@@ -0,0 +1,111 @@
+// Filename: internal/validator/text.go
+package validator
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Put text in NFC form, so "é" typed as e plus a combining accent is
+// stored (and counted) the same as a single "é"
+func NormalizeNFC(value string) string {
+	return norm.NFC.String(value)
+}
+
+// Tidy single line text from a client: NFC form, no space at either end
+// and every run of whitespace (including line breaks) made a single space
+func CleanText(value string) string {
+	return strings.Join(strings.Fields(NormalizeNFC(value)), " ")
+}
+
+// Tidy multi-line text from a client: NFC form and no space at either end.
+// Line breaks inside the text are kept
+func CleanMultilineText(value string) string {
+	return strings.TrimSpace(NormalizeNFC(value))
+}
+
+// Check if the text is blank, i.e. empty or only whitespace
+func NotBlank(value string) bool {
+	return strings.TrimSpace(value) != ""
+}
+
+// Check that the text has no space at either end
+func IsTrimmed(value string) bool {
+	return value == strings.TrimSpace(value)
+}
+
+// Check that the text is valid UTF-8 in NFC form
+func IsNFC(value string) bool {
+	return utf8.ValidString(value) && norm.NFC.IsNormalString(value)
+}
+
+// Check that the text has no control characters, such as NUL or escape
+// codes, other than the ones allowed (e.g. '\n' for multi-line text)
+func NoControlCharacters(value string, allowed ...rune) bool {
+	for _, r := range value {
+		if unicode.IsControl(r) && !strings.ContainsRune(string(allowed), r) {
+			return false
+		}
+	}
+	return true
+}
+
+// Count the characters (code points) in the text
+func RuneCount(value string) int {
+	return utf8.RuneCountInString(value)
+}
+
+// Count the characters in the text the way a reader would. A letter with
+// combining accents, an emoji with a skin tone, a flag or a family emoji
+// joined with zero width joiners each count once. This is a close
+// approximation of Unicode grapheme clusters, good enough for length limits
+func GraphemeCount(value string) int {
+	count := 0
+	previous := rune(-1)
+	regionalIndicators := 0
+
+	for _, r := range value {
+		switch {
+		// combining marks, variation selectors and skin tones belong to
+		// the character before them
+		case unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc, unicode.Variation_Selector),
+			r >= 0x1F3FB && r <= 0x1F3FF:
+		// a zero width joiner glues the characters either side together
+		case r == '\u200d', previous == '\u200d':
+		// \r\n is a single line break
+		case r == '\n' && previous == '\r':
+		// flags are pairs of regional indicators
+		case r >= 0x1F1E6 && r <= 0x1F1FF:
+			if regionalIndicators%2 == 0 {
+				count++
+			}
+			regionalIndicators++
+		default:
+			count++
+		}
+
+		if r < 0x1F1E6 || r > 0x1F1FF {
+			regionalIndicators = 0
+		}
+		previous = r
+	}
+	return count
+}
+
+// Check that the text has no more than n characters, as a reader counts them
+func MaxChars(value string, n int) bool {
+	return GraphemeCount(value) <= n
+}
+
+// Check that the text has at least n characters, as a reader counts them
+func MinChars(value string, n int) bool {
+	return GraphemeCount(value) >= n
+}
+
+// Check that the text has no more than n code points
+func MaxRunes(value string, n int) bool {
+	return RuneCount(value) <= n
+}
@@ -0,0 +1,45 @@
+// Filename: internal/validator/text_test.go
+package validator
+
+import "testing"
+
+func TestGraphemeCount(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{"hello", 5},
+		{"café", 4},
+		{"café", 4},       // e plus a combining accent
+		{"Здравствуй", 10}, // Cyrillic
+		{"你好世界", 4},        // Chinese
+		{"👍🏽", 1},          // emoji with a skin tone
+		{"👨‍👩‍👧", 1},       // family joined with zero width joiners
+		{"🇧🇿🇯🇲", 2},        // two flags
+		{"a\r\nb", 3},
+	}
+
+	for _, tt := range tests {
+		if got := GraphemeCount(tt.value); got != tt.want {
+			t.Errorf("GraphemeCount(%q): expected %d, got %d", tt.value, tt.want, got)
+		}
+	}
+}
+
+func TestCleanText(t *testing.T) {
+	got := CleanText("  Stay\thungry,\n  stay  café ")
+	want := "Stay hungry, stay café"
+	if got != want {
+		t.Errorf("expected: %q, got: %q", want, got)
+	}
+	if !IsNFC(got) || !IsTrimmed(got) || !NoControlCharacters(got) {
+		t.Errorf("expected %q to pass the text checks", got)
+	}
+
+	if NoControlCharacters("bell\a") {
+		t.Error("expected a control character to be caught")
+	}
+	if !NoControlCharacters("two\nlines", '\n') {
+		t.Error("expected an allowed control character to pass")
+	}
+}
@@ -2,6 +2,7 @@
 package validator
 
 import (
+	"maps"
 	"regexp"
 	"slices"
 )
@@ -9,15 +10,45 @@ import (
 // Regex to check if an email is valid
 var EmailRX = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+/=?^_{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
 
+// One reason a field is invalid. The code never changes so clients can
+// rely on it, the params fill in the message (e.g. the maximum length)
+type Error struct {
+	Code    string         `json:"code"`
+	Params  map[string]any `json:"params,omitempty"`
+	Message string         `json:"message"`
+}
+
+// Build an error from its code and name/value pairs of parameters, e.g.
+// NewError("max_chars", "max", 100). The message is filled in in English
+func NewError(code string, params ...any) Error {
+	e := Error{Code: code}
+	if len(params) > 0 {
+		e.Params = make(map[string]any, len(params)/2)
+		for i := 0; i+1 < len(params); i += 2 {
+			e.Params[params[i].(string)] = params[i+1]
+		}
+	}
+	e.Message = Message(DefaultLanguage, e.Code, e.Params)
+	return e
+}
+
+// The same error with its message in another language
+func (e Error) Localize(lang string) Error {
+	e.Message = Message(lang, e.Code, e.Params)
+	return e
+}
+
 type Validator struct {
-	Errors map[string]string
+	Errors      map[string]string  // the first message for each field, the shape older clients expect
+	FieldErrors map[string][]Error // every error for each field
 }
 
 // Construct a new Validator and return a pointer to it
 // All validation errors go into this one Validtor instance
 func New() *Validator {
 	return &Validator{
-		Errors: make(map[string]string),
+		Errors:      make(map[string]string),
+		FieldErrors: make(map[string][]Error),
 	}
 }
 
@@ -26,20 +57,44 @@ func (v *Validator) IsEmpty() bool {
 	return len(v.Errors) == 0
 }
 
-// Add a new error entry to the Validator's error map
-// Check first if an entry with the same key does not already exist
-func (v *Validator) AddError(key string, message string) {
+// Add a new error entry for a field. A field can have several errors but
+// the same error (e.g. from checking each tag) is only added once
+func (v *Validator) AddError(key string, e Error) {
 	_, exists := v.Errors[key]
 	if !exists {
-		v.Errors[key] = message
+		v.Errors[key] = e.Message
+	}
+
+	for _, existing := range v.FieldErrors[key] {
+		if existing.Code == e.Code && maps.Equal(existing.Params, e.Params) {
+			return
+		}
 	}
+	v.FieldErrors[key] = append(v.FieldErrors[key], e)
 }
 
 // If any validation check returns false, then make an entry into our validator's error map
-func (v *Validator) Check(acceptable bool, key string, message string) {
+func (v *Validator) Check(acceptable bool, key string, e Error) {
 	if !acceptable {
-		v.AddError(key, message)
+		v.AddError(key, e)
+	}
+}
+
+// The errors with their messages in the given language, in both shapes
+func (v *Validator) Localize(lang string) (map[string]string, map[string][]Error) {
+	errors := make(map[string]string, len(v.Errors))
+	fieldErrors := make(map[string][]Error, len(v.FieldErrors))
+
+	for key, list := range v.FieldErrors {
+		localized := make([]Error, len(list))
+		for i, e := range list {
+			localized[i] = e.Localize(lang)
+		}
+		errors[key] = localized[0].Message
+		fieldErrors[key] = localized
 	}
+
+	return errors, fieldErrors
 }
 
 // Check for permitted values
@@ -51,3 +106,24 @@ func PermittedValue(value string, permittedValues ...string) bool {
 func Matches(value string, rx *regexp.Regexp) bool {
 	return rx.MatchString(value)
 }
+
+// Check that all the values in a slice are different
+func Unique[T comparable](values []T) bool {
+	uniqueValues := make(map[T]bool)
+
+	for _, value := range values {
+		uniqueValues[value] = true
+	}
+
+	return len(values) == len(uniqueValues)
+}
+
+// Check that every value in a slice is one of the permitted values
+func PermittedValues[T comparable](values []T, permittedValues ...T) bool {
+	for _, value := range values {
+		if !slices.Contains(permittedValues, value) {
+			return false
+		}
+	}
+	return true
+}
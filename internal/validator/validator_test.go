@@ -0,0 +1,50 @@
+// Filename: internal/validator/validator_test.go
+package validator
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestValidatorErrors(t *testing.T) {
+	v := New()
+	v.Check(false, "content", NewError("required"))
+	v.Check(false, "content", NewError("max_chars", "max", 100))
+	// the same error again, e.g. from the next tag, is only kept once
+	v.Check(false, "content", NewError("max_chars", "max", 100))
+	v.Check(true, "author", NewError("required"))
+
+	if got := v.Errors["content"]; got != "must be provided" {
+		t.Errorf("expected the first message in Errors, got: %q", got)
+	}
+	if got := len(v.FieldErrors["content"]); got != 2 {
+		t.Fatalf("expected: 2 errors for content, got: %d", got)
+	}
+	if _, exists := v.FieldErrors["author"]; exists {
+		t.Error("expected no errors for author")
+	}
+
+	errors, fieldErrors := v.Localize("es")
+	if got := errors["content"]; got != "es obligatorio" {
+		t.Errorf("expected the Spanish message, got: %q", got)
+	}
+	second := fieldErrors["content"][1]
+	if second.Code != "max_chars" || second.Message != "no debe tener más de 100 caracteres" {
+		t.Errorf("unexpected error %+v", second)
+	}
+
+	// an unknown language falls back to English
+	if got := NewError("positive").Localize("xx").Message; got != "must be greater than zero" {
+		t.Errorf("expected the English message, got: %q", got)
+	}
+}
+
+func TestCatalogue(t *testing.T) {
+	codes := slices.Sorted(maps.Keys(catalogue[DefaultLanguage]))
+	for _, lang := range Languages() {
+		if got := slices.Sorted(maps.Keys(catalogue[lang])); !slices.Equal(got, codes) {
+			t.Errorf("expected %q to have a message for every code", lang)
+		}
+	}
+}
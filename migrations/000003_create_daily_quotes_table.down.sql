@@ -0,0 +1,2 @@
+-- Filename: migrations/000003_create_daily_quotes_table.down.sql
+DROP TABLE IF EXISTS daily_quotes;
@@ -0,0 +1,9 @@
+-- Filename: migrations/000003_create_daily_quotes_table.up.sql
+CREATE TABLE IF NOT EXISTS daily_quotes (
+    day date PRIMARY KEY,
+    quote_id bigint NOT NULL REFERENCES quotes ON DELETE CASCADE,
+    pinned bool NOT NULL DEFAULT false,
+    created_at timestamp(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS daily_quotes_quote_id_idx ON daily_quotes (quote_id);
@@ -0,0 +1,5 @@
+-- Filename: migrations/000006_add_quotes_user_id.down.sql
+DELETE FROM permissions WHERE code = 'quotes:moderate';
+
+DROP INDEX IF EXISTS quotes_user_id_idx;
+ALTER TABLE quotes DROP COLUMN IF EXISTS user_id;
@@ -0,0 +1,9 @@
+-- Filename: migrations/000006_add_quotes_user_id.up.sql
+-- existing quotes have no known owner so the column stays nullable
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS user_id bigint REFERENCES users ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS quotes_user_id_idx ON quotes (user_id);
+
+INSERT INTO permissions (code)
+VALUES ('quotes:moderate')
+ON CONFLICT (code) DO NOTHING;
@@ -0,0 +1,3 @@
+-- Filename: migrations/000007_add_quotes_tags.down.sql
+DROP INDEX IF EXISTS quotes_tags_idx;
+ALTER TABLE quotes DROP COLUMN IF EXISTS tags;
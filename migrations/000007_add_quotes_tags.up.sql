@@ -0,0 +1,4 @@
+-- Filename: migrations/000007_add_quotes_tags.up.sql
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS tags text[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS quotes_tags_idx ON quotes USING GIN (tags);
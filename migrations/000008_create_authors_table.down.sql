@@ -0,0 +1,7 @@
+-- Filename: migrations/000008_create_authors_table.down.sql
+DELETE FROM permissions WHERE code = 'authors:write';
+
+DROP INDEX IF EXISTS quotes_author_id_idx;
+ALTER TABLE quotes DROP COLUMN IF EXISTS author_id;
+
+DROP TABLE IF EXISTS authors;
@@ -0,0 +1,36 @@
+-- Filename: migrations/000008_create_authors_table.up.sql
+CREATE TABLE IF NOT EXISTS authors (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    aliases text[] NOT NULL DEFAULT '{}',
+    bio text NOT NULL DEFAULT '',
+    birth_year integer,
+    death_year integer,
+    created_at timestamp(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    version integer NOT NULL DEFAULT 1
+);
+
+-- "einstein" and "Einstein" are the same author
+CREATE UNIQUE INDEX IF NOT EXISTS authors_name_idx ON authors (lower(name));
+
+-- one author for each distinct author string already in use
+INSERT INTO authors (name)
+SELECT DISTINCT ON (lower(author)) author
+FROM quotes
+ORDER BY lower(author), author
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS author_id bigint REFERENCES authors ON DELETE RESTRICT;
+
+UPDATE quotes
+SET author_id = authors.id
+FROM authors
+WHERE lower(authors.name) = lower(quotes.author);
+
+ALTER TABLE quotes ALTER COLUMN author_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS quotes_author_id_idx ON quotes (author_id);
+
+INSERT INTO permissions (code)
+VALUES ('authors:write')
+ON CONFLICT (code) DO NOTHING;
@@ -0,0 +1,6 @@
+-- Filename: migrations/000009_add_quotes_search_vector.down.sql
+DROP INDEX IF EXISTS quotes_author_tsv_idx;
+DROP INDEX IF EXISTS quotes_content_tsv_idx;
+DROP INDEX IF EXISTS quotes_search_vector_idx;
+
+ALTER TABLE quotes DROP COLUMN IF EXISTS search_vector;
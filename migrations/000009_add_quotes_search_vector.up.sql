@@ -0,0 +1,13 @@
+-- Filename: migrations/000009_add_quotes_search_vector.up.sql
+-- content matches rank higher (A) than author matches (B)
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', content), 'A') ||
+        setweight(to_tsvector('simple', author), 'B')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS quotes_search_vector_idx ON quotes USING GIN (search_vector);
+
+-- the content/author filters on the quotes list use these expressions
+CREATE INDEX IF NOT EXISTS quotes_content_tsv_idx ON quotes USING GIN (to_tsvector('simple', content));
+CREATE INDEX IF NOT EXISTS quotes_author_tsv_idx ON quotes USING GIN (to_tsvector('simple', author));
@@ -0,0 +1,4 @@
+-- Filename: migrations/000010_add_trigram_indexes.down.sql
+DROP INDEX IF EXISTS authors_name_trgm_idx;
+DROP INDEX IF EXISTS quotes_author_trgm_idx;
+DROP INDEX IF EXISTS quotes_content_trgm_idx;
@@ -0,0 +1,7 @@
+-- Filename: migrations/000011_add_quotes_updated_at_and_views.down.sql
+DROP INDEX IF EXISTS quotes_views_idx;
+DROP INDEX IF EXISTS quotes_updated_at_idx;
+DROP INDEX IF EXISTS quotes_created_at_idx;
+
+ALTER TABLE quotes DROP COLUMN IF EXISTS views;
+ALTER TABLE quotes DROP COLUMN IF EXISTS updated_at;
@@ -0,0 +1,10 @@
+-- Filename: migrations/000011_add_quotes_updated_at_and_views.up.sql
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS updated_at timestamp(0) WITH TIME ZONE NOT NULL DEFAULT NOW();
+UPDATE quotes SET updated_at = created_at;
+
+-- how many times a quote has been displayed, used for the popularity sort
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS views bigint NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS quotes_created_at_idx ON quotes (created_at, id);
+CREATE INDEX IF NOT EXISTS quotes_updated_at_idx ON quotes (updated_at, id);
+CREATE INDEX IF NOT EXISTS quotes_views_idx ON quotes (views, id);
@@ -0,0 +1,4 @@
+-- Filename: migrations/000012_add_quotes_fingerprint.down.sql
+DROP INDEX IF EXISTS quotes_fingerprint_idx;
+
+ALTER TABLE quotes DROP COLUMN IF EXISTS fingerprint;
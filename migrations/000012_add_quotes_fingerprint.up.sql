@@ -0,0 +1,8 @@
+-- Filename: migrations/000012_add_quotes_fingerprint.up.sql
+-- The API fills this in with data.Fingerprint. SQL can't normalise text the
+-- same way Go does (what counts as punctuation, lowercasing and spaces all
+-- differ) so the quotes already in the table are filled in by running
+-- cmd/fingerprints after this migration
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS fingerprint text;
+
+CREATE UNIQUE INDEX IF NOT EXISTS quotes_fingerprint_idx ON quotes (fingerprint);
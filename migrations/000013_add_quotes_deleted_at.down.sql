@@ -0,0 +1,9 @@
+-- Filename: migrations/000013_add_quotes_deleted_at.down.sql
+-- the trash is emptied for good
+DELETE FROM quotes WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS quotes_fingerprint_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS quotes_fingerprint_idx ON quotes (fingerprint);
+
+DROP INDEX IF EXISTS quotes_deleted_at_idx;
+ALTER TABLE quotes DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,9 @@
+-- Filename: migrations/000013_add_quotes_deleted_at.up.sql
+-- deleted quotes stay in the table (in the trash) until they are purged
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS deleted_at timestamp(0) WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS quotes_deleted_at_idx ON quotes (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- a quote in the trash doesn't stop the same quote being added again
+DROP INDEX IF EXISTS quotes_fingerprint_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS quotes_fingerprint_idx ON quotes (fingerprint) WHERE deleted_at IS NULL;
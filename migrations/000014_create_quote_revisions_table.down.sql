@@ -0,0 +1,2 @@
+-- Filename: migrations/000014_create_quote_revisions_table.down.sql
+DROP TABLE IF EXISTS quote_revisions;
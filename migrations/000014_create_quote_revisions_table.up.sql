@@ -0,0 +1,19 @@
+-- Filename: migrations/000014_create_quote_revisions_table.up.sql
+-- what a quote looked like at each version
+CREATE TABLE IF NOT EXISTS quote_revisions (
+    quote_id bigint NOT NULL REFERENCES quotes ON DELETE CASCADE,
+    version integer NOT NULL,
+    content text NOT NULL,
+    author text NOT NULL,
+    author_id bigint NOT NULL, -- not a foreign key so old revisions don't stop an author being deleted
+    tags text[] NOT NULL DEFAULT '{}',
+    user_id bigint REFERENCES users ON DELETE SET NULL, -- who made this version
+    created_at timestamp(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (quote_id, version)
+);
+
+-- the quotes as they are now are the first revisions we know of
+INSERT INTO quote_revisions (quote_id, version, content, author, author_id, tags, user_id, created_at)
+SELECT id, version, content, author, author_id, tags, user_id, updated_at
+FROM quotes
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,7 @@
+-- Filename: migrations/000015_add_quotes_status.down.sql
+DROP INDEX IF EXISTS quotes_pending_idx;
+ALTER TABLE quotes DROP COLUMN IF EXISTS moderated_at;
+ALTER TABLE quotes DROP COLUMN IF EXISTS moderated_by;
+ALTER TABLE quotes DROP COLUMN IF EXISTS moderation_reason;
+ALTER TABLE quotes DROP CONSTRAINT IF EXISTS quotes_status_check;
+ALTER TABLE quotes DROP COLUMN IF EXISTS status;
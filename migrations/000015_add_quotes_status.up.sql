@@ -0,0 +1,20 @@
+-- Filename: migrations/000015_add_quotes_status.up.sql
+-- new quotes wait for a moderator, the quotes we already have are approved
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS status text NOT NULL DEFAULT 'approved';
+ALTER TABLE quotes ALTER COLUMN status SET DEFAULT 'pending';
+-- ADD CONSTRAINT has no IF NOT EXISTS, so check for it first
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'quotes_status_check' AND conrelid = 'quotes'::regclass) THEN
+        ALTER TABLE quotes ADD CONSTRAINT quotes_status_check CHECK (status IN ('pending', 'approved', 'rejected'));
+    END IF;
+END
+$$;
+
+-- why a moderator made their decision, and who made it when
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS moderation_reason text NOT NULL DEFAULT '';
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS moderated_by bigint REFERENCES users ON DELETE SET NULL;
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS moderated_at timestamp(0) WITH TIME ZONE;
+
+-- the moderation queue is read oldest first
+CREATE INDEX IF NOT EXISTS quotes_pending_idx ON quotes (created_at) WHERE status = 'pending' AND deleted_at IS NULL;
@@ -0,0 +1,17 @@
+-- Filename: migrations/000016_exclude_rejected_from_fingerprint_idx.down.sql
+-- rejected quotes that were added again lose their fingerprint (the other
+-- quote keeps it) so the stricter index can be built
+UPDATE quotes
+SET fingerprint = NULL
+WHERE id IN (
+    SELECT id
+    FROM (
+        SELECT id, row_number() OVER (PARTITION BY fingerprint ORDER BY status = 'rejected', id) AS n
+        FROM quotes
+        WHERE fingerprint IS NOT NULL AND deleted_at IS NULL
+    ) AS numbered
+    WHERE n > 1
+);
+
+DROP INDEX IF EXISTS quotes_fingerprint_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS quotes_fingerprint_idx ON quotes (fingerprint) WHERE deleted_at IS NULL;
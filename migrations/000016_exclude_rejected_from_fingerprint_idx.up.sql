@@ -0,0 +1,4 @@
+-- Filename: migrations/000016_exclude_rejected_from_fingerprint_idx.up.sql
+-- a rejected quote doesn't stop somebody else adding the same quote
+DROP INDEX IF EXISTS quotes_fingerprint_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS quotes_fingerprint_idx ON quotes (fingerprint) WHERE deleted_at IS NULL AND status <> 'rejected';